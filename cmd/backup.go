@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/egregors/zenmoney-backup/srv"
+)
+
+// BackupCmd is backup command settings, it makes a single backup and exits.
+type BackupCmd struct{}
+
+// backupAll makes a single backup of every server concurrently, failure of one doesn't stop others.
+func backupAll(ctx context.Context, servers []*srv.Server) error {
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Go(func() { errs[i] = withProfile(s.Profile(), s.RunOnce(ctx)) })
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Exit codes of one-shot backup, so external schedulers can tell failures apart.
+const (
+	exitError        = 1 // invalid settings and other errors
+	exitClientError  = 3
+	exitAPIError     = 4
+	exitStorageError = 5
+)
+
+// exitCode returns process exit code for err returned by srv.Server.RunOnce. If backups of several
+// profiles failed, the code of the first one is returned.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var se *srv.StageError
+	if !errors.As(err, &se) {
+		return exitError
+	}
+	switch se.Stage {
+	case srv.StageClient:
+		return exitClientError
+	case srv.StageExport:
+		return exitAPIError
+	case srv.StageSave, srv.StageMirror, srv.StageRetention:
+		return exitStorageError
+	default:
+		return exitError
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/egregors/zenmoney-backup/srv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	cause := errors.New("oops")
+	tbl := []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{cause, 1},
+		{&srv.StageError{Stage: srv.StageClient, Err: cause}, 3},
+		{&srv.StageError{Stage: srv.StageExport, Err: cause}, 4},
+		{&srv.StageError{Stage: srv.StageEncode, Err: cause}, 1},
+		{&srv.StageError{Stage: srv.StageSave, Err: cause}, 5},
+		{&srv.StageError{Stage: srv.StageMirror, Err: cause}, 5},
+		{fmt.Errorf("wrapped: %w", &srv.StageError{Stage: srv.StageRetention, Err: cause}), 5},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.code, exitCode(tt.err), "%v", tt.err)
+	}
+}
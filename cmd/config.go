@@ -0,0 +1,357 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/egregors/zenmoney-backup/codec"
+	"github.com/egregors/zenmoney-backup/notifier"
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigCmd is config command, it checks and shows settings.
+type ConfigCmd struct {
+	Validate struct{} `command:"validate" description:"Check settings and report all found errors"`
+	Print    struct{} `command:"print" description:"Print effective settings as YAML, secrets are redacted"`
+}
+
+// config is a parsed config file. Keys are names of env variables in lower case, e.g. sleep_time.
+type config struct {
+	values   map[string][]string
+	profiles map[string]map[string][]string // settings of profiles by name, override global ones
+}
+
+const redacted = "***"
+
+// loadConfig reads YAML (.yaml, .yml) or TOML (.toml) config file.
+func loadConfig(path string) (config, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return config{}, err
+	}
+
+	raw := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(bs, &raw)
+	case ".toml":
+		err = toml.Unmarshal(bs, &raw)
+	default:
+		return config{}, fmt.Errorf("unknown config format %q, .yaml, .yml or .toml is expected", ext)
+	}
+	if err != nil {
+		return config{}, fmt.Errorf("can't parse %s: %w", path, err)
+	}
+
+	res := config{values: map[string][]string{}, profiles: map[string]map[string][]string{}}
+	var errs []error
+	// profiles is a list of names, or settings of profiles by name
+	if sections, ok := raw["profiles"].(map[string]any); ok {
+		delete(raw, "profiles")
+		for name, section := range sections {
+			res.values["profiles"] = append(res.values["profiles"], name)
+			m, ok := section.(map[string]any)
+			if !ok && section != nil {
+				errs = append(errs, fmt.Errorf("profile %s: settings are expected", name))
+				continue
+			}
+			res.profiles[name] = map[string][]string{}
+			if err := parseConfigValues(m, res.profiles[name]); err != nil {
+				errs = append(errs, withProfile("profile "+name, err))
+			}
+		}
+		slices.Sort(res.values["profiles"])
+	}
+	if err := parseConfigValues(raw, res.values); err != nil {
+		errs = append(errs, err)
+	}
+	return res, errors.Join(errs...)
+}
+
+func parseConfigValues(raw map[string]any, res map[string][]string) error {
+	var errs []error
+	for _, key := range sortedKeys(raw) {
+		var values []string
+		items, isList := raw[key].([]any)
+		if !isList {
+			items = []any{raw[key]}
+		}
+		for _, item := range items {
+			switch v := item.(type) {
+			case string:
+				values = append(values, v)
+			case bool, int, int64, uint64, float64:
+				values = append(values, fmt.Sprint(v))
+			default:
+				errs = append(errs, fmt.Errorf("%s: unsupported value %v", key, item))
+			}
+		}
+		res[key] = values
+	}
+	return errors.Join(errs...)
+}
+
+// applyConfig sets options which are not set by flags or env from config file, so the precedence is
+// flags, env, config file and then defaults. Settings of profiles are kept to be applied by Opts.profiles.
+func applyConfig(p *flags.Parser, opts *Opts) error {
+	cfg, err := loadConfig(opts.ConfigFile)
+	// apply what is parsed, so all errors are reported by config validate
+	errs := []error{err}
+
+	byKey := map[string]*flags.Option{}
+	for _, o := range optionsOf(p) {
+		byKey[configKey(o)] = o
+	}
+	for _, key := range sortedKeys(cfg.values) {
+		o, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown option %q", key))
+			continue
+		}
+		if _, inEnv := os.LookupEnv(o.EnvDefaultKey); inEnv || (o.IsSet() && !o.IsSetDefault()) {
+			continue
+		}
+		if err := setOption(opts, o, cfg.values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", key, err))
+		}
+	}
+	for _, name := range sortedKeys(cfg.profiles) {
+		for _, key := range sortedKeys(cfg.profiles[name]) {
+			if _, ok := byKey[key]; !ok {
+				errs = append(errs, fmt.Errorf("profile %s: unknown option %q", name, key))
+			}
+		}
+	}
+	opts.profileConfig = cfg.profiles
+	return errors.Join(errs...)
+}
+
+// optionsOf returns top level options of the parser which can be set by env.
+func optionsOf(p *flags.Parser) []*flags.Option {
+	var res []*flags.Option
+	for _, g := range p.Groups() {
+		for _, o := range g.Options() {
+			if o.EnvDefaultKey != "" {
+				res = append(res, o)
+			}
+		}
+	}
+	return res
+}
+
+// configKey returns name of the option in config file.
+func configKey(o *flags.Option) string {
+	return strings.ToLower(o.EnvDefaultKey)
+}
+
+// setOption replaces value of option of opts by values.
+func setOption(opts *Opts, o *flags.Option, values []string) error {
+	// slices are appended to by Set, drop the previous values first
+	field := reflect.ValueOf(opts).Elem().FieldByName(o.Field().Name)
+	if field.Kind() == reflect.Slice {
+		field.SetZero()
+	}
+	for _, v := range values {
+		if err := o.Set(&v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateConfig checks settings of every profile, errors of config file are reported as well.
+func validateConfig(opts Opts) error {
+	profiles, err := opts.profiles()
+	errs := []error{opts.configErr, err}
+	for _, p := range profiles {
+		err := p.opts.validate()
+		var joined interface{ Unwrap() []error }
+		if p.name == "" || !errors.As(err, &joined) {
+			errs = append(errs, err)
+			continue
+		}
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, fmt.Errorf("profile %s: %w", p.name, e))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks settings, returning all found errors.
+func (opts Opts) validate() error {
+	var errs []error
+	if opts.Token == "" {
+		errs = append(errs, errors.New("zen_token is required"))
+	}
+	for _, d := range []struct{ key, value string }{
+		{"sleep_time", opts.SleepTime},
+		{"ready_max_age", opts.ReadyMaxAge},
+		{"retry_delay", opts.RetryDelay},
+		{"retry_max_delay", opts.RetryMaxDelay},
+		{"full_every", opts.FullEvery},
+	} {
+		if _, err := time.ParseDuration(d.value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", d.key, err))
+		}
+	}
+	if opts.Schedule != "" {
+		if _, err := makeSchedule(opts.Schedule); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("timeout must be a positive integer, got %d", opts.Timeout))
+	}
+	if opts.RetryAttempts < 1 {
+		errs = append(errs, fmt.Errorf("retry_attempts must be a positive integer, got %d", opts.RetryAttempts))
+	}
+	if _, err := notifier.NewRouter(opts.NotifyURL, reportPeriod); err != nil {
+		errs = append(errs, fmt.Errorf("invalid notify_url: %w", err))
+	}
+	if opts.TelegramToken != "" && opts.TelegramChatID == "" {
+		errs = append(errs, errors.New("telegram_chat_id is required for telegram notifications"))
+	}
+	if opts.SMTPHost != "" && (opts.SMTPFrom == "" || len(opts.SMTPTo) == 0) {
+		errs = append(errs, errors.New("smtp_from and smtp_to are required for email notifications"))
+	}
+	if opts.WebhookURL != "" {
+		if u, err := url.Parse(opts.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, errors.New("invalid webhook_url, http(s) URL is expected"))
+		}
+	}
+	if _, err := parseHeaders(opts.WebhookHeaders); err != nil {
+		errs = append(errs, err)
+	}
+	if opts.WebhookTemplate != "" {
+		if _, err := notifier.NewWebhook(notifier.WebhookOpts{Template: opts.WebhookTemplate}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if opts.KeepLast < 0 || opts.KeepDaily < 0 || opts.KeepWeekly < 0 || opts.KeepMonthly < 0 {
+		errs = append(errs, errors.New("keep_* options must not be negative"))
+	}
+	if opts.Compress != "" && opts.Compress != "none" {
+		if _, err := codec.NewCompressor(opts.Compress, opts.CompressLevel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(opts.AgeRecipients) > 0 || opts.AgePassphrase != "" {
+		if _, err := codec.NewAge(opts.AgeRecipients, opts.AgePassphrase); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := makeExporters(opts.ExportFormats); err != nil {
+		errs = append(errs, err)
+	}
+	switch opts.Storage {
+	case "", "local":
+	case "s3":
+		if opts.S3Bucket == "" {
+			errs = append(errs, errors.New("s3_bucket is required for s3 storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown storage %q", opts.Storage))
+	}
+	return errors.Join(errs...)
+}
+
+// printConfig writes effective settings as YAML config file, with settings of profiles which
+// differ from global ones. Options tagged as secret are redacted.
+func printConfig(opts Opts, out io.Writer) error {
+	profiles, err := opts.profiles()
+	if err != nil {
+		return err
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	global := map[string]any{}
+	for _, o := range options(&opts) {
+		key := configKey(o)
+		if key == "config" || (key == "profiles" && len(opts.Profiles) > 0) {
+			continue
+		}
+		global[key] = o.Value()
+		doc.Content = append(doc.Content, scalarNode("!!str", key), optionNode(o))
+	}
+
+	if len(opts.Profiles) > 0 {
+		sections := &yaml.Node{Kind: yaml.MappingNode}
+		for _, p := range profiles {
+			section := &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+			for _, o := range options(&p.opts) {
+				key := configKey(o)
+				if key == "config" || key == "profiles" {
+					continue
+				}
+				if !reflect.DeepEqual(o.Value(), global[key]) {
+					section.Content = append(section.Content, scalarNode("!!str", key), optionNode(o))
+					section.Style = 0
+				}
+			}
+			sections.Content = append(sections.Content, scalarNode("!!str", p.name), section)
+		}
+		doc.Content = append(doc.Content, scalarNode("!!str", "profiles"), sections)
+	}
+
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// options returns top level options bound to opts.
+func options(opts *Opts) []*flags.Option {
+	return optionsOf(flags.NewParser(opts, flags.None))
+}
+
+func optionNode(o *flags.Option) *yaml.Node {
+	v := reflect.ValueOf(o.Value())
+	if o.Field().Tag.Get("secret") == "true" && !v.IsZero() {
+		return scalarNode("!!str", redacted)
+	}
+	if v.Kind() == reflect.Slice {
+		res := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := range v.Len() {
+			res.Content = append(res.Content, valueNode(v.Index(i)))
+		}
+		return res
+	}
+	return valueNode(v)
+}
+
+func valueNode(v reflect.Value) *yaml.Node {
+	switch v.Kind() {
+	case reflect.Bool:
+		return scalarNode("!!bool", strconv.FormatBool(v.Bool()))
+	case reflect.Int:
+		return scalarNode("!!int", strconv.FormatInt(v.Int(), 10))
+	default:
+		return scalarNode("!!str", v.String())
+	}
+}
+
+func scalarNode(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	slices.Sort(res)
+	return res
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	want := config{
+		values: map[string][]string{
+			"zen_token":      {"token"},
+			"timeout":        {"30"},
+			"dedup":          {"true"},
+			"age_recipients": {"age1one", "age1two"},
+			"profiles":       {"home", "work"},
+		},
+		profiles: map[string]map[string][]string{
+			"home": {"s3_prefix": {"home"}},
+			"work": {},
+		},
+	}
+
+	cfg, err := loadConfig(writeConfig(t, "zenb.yaml", `
+zen_token: token
+timeout: 30
+dedup: true
+age_recipients: [age1one, age1two]
+profiles:
+  home:
+    s3_prefix: home
+  work:
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, want, cfg)
+
+	cfg, err = loadConfig(writeConfig(t, "zenb.toml", `
+zen_token = "token"
+timeout = 30
+dedup = true
+age_recipients = ["age1one", "age1two"]
+
+[profiles.home]
+s3_prefix = "home"
+
+[profiles.work]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, want, cfg)
+
+	cfg, err = loadConfig(writeConfig(t, "zenb.yml", "profiles: [home, work]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"home", "work"}, cfg.values["profiles"])
+	assert.Empty(t, cfg.profiles)
+
+	_, err = loadConfig(writeConfig(t, "zenb.yaml", "s3: {bucket: backups}\nprofiles: {home: 1}\n"))
+	assert.ErrorContains(t, err, "s3: unsupported value map[bucket:backups]")
+	assert.ErrorContains(t, err, "profile home: settings are expected")
+
+	_, err = loadConfig(writeConfig(t, "zenb.yaml", "timeout: [1\n"))
+	assert.ErrorContains(t, err, "can't parse")
+
+	_, err = loadConfig(writeConfig(t, "zenb.ini", "timeout=1\n"))
+	assert.EqualError(t, err, `unknown config format ".ini", .yaml, .yml or .toml is expected`)
+
+	_, err = loadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestApplyConfig(t *testing.T) {
+	path := writeConfig(t, "zenb.yaml", `
+zen_token: file_token
+sleep_time: 1h
+timeout: 30
+keep_daily: 7
+age_recipients: [age1file]
+profiles:
+  home:
+    zen_token: home_token
+`)
+	t.Setenv("SLEEP_TIME", "2h")
+
+	var opts Opts
+	p := flags.NewParser(&opts, flags.None)
+	p.SubcommandsOptional = true
+	_, err := p.ParseArgs([]string{"--config", path, "--timeout", "5", "--age_recipient", "age1flag"})
+	assert.NoError(t, err)
+	assert.NoError(t, applyConfig(p, &opts))
+
+	assert.Equal(t, "file_token", opts.Token)
+	assert.Equal(t, "2h", opts.SleepTime, "env takes precedence")
+	assert.Equal(t, 5, opts.Timeout, "flag takes precedence")
+	assert.Equal(t, []string{"age1flag"}, opts.AgeRecipients, "flag takes precedence")
+	assert.Equal(t, 7, opts.KeepDaily)
+	assert.Equal(t, "168h", opts.FullEvery, "default is kept")
+	assert.Equal(t, []string{"home"}, opts.Profiles)
+
+	profiles, err := opts.profiles()
+	assert.NoError(t, err)
+	if assert.Len(t, profiles, 1) {
+		assert.Equal(t, "home_token", profiles[0].opts.Token)
+	}
+
+	t.Setenv("HOME_ZEN_TOKEN", "env_token")
+	profiles, err = opts.profiles()
+	assert.NoError(t, err)
+	if assert.Len(t, profiles, 1) {
+		assert.Equal(t, "env_token", profiles[0].opts.Token, "env takes precedence over config file")
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	path := writeConfig(t, "zenb.yaml", `
+timeout: soon
+storage: ftp
+bogus: 1
+profiles:
+  home:
+    unknown: 1
+`)
+	var opts Opts
+	p := flags.NewParser(&opts, flags.None)
+	p.SubcommandsOptional = true
+	_, err := p.ParseArgs([]string{"--config", path})
+	assert.NoError(t, err)
+
+	err = applyConfig(p, &opts)
+	assert.ErrorContains(t, err, `unknown option "bogus"`)
+	assert.ErrorContains(t, err, "invalid timeout")
+	assert.ErrorContains(t, err, "invalid storage: Invalid value `ftp'")
+	assert.ErrorContains(t, err, `profile home: unknown option "unknown"`)
+}
+
+func TestOpts_validate(t *testing.T) {
+	valid := Opts{Token: "token", SleepTime: "24h", ReadyMaxAge: "48h", RetryDelay: "1m", RetryMaxDelay: "30m",
+		FullEvery: "168h", Timeout: 10, RetryAttempts: 3, Storage: "local", Compress: "none"}
+	assert.NoError(t, valid.validate())
+
+	invalid := valid
+	invalid.Token = ""
+	invalid.SleepTime = "daily"
+	invalid.Schedule = "0 3 * *"
+	invalid.Timeout = 0
+	invalid.NotifyURL = []string{"ntfy://topic", "ntfy.sh/topic"}
+	invalid.KeepDaily = -1
+	invalid.Compress = "gzip"
+	invalid.CompressLevel = 42
+	invalid.Storage = "s3"
+	invalid.TelegramToken = "123:abc"
+	invalid.SMTPHost = "smtp.example.com"
+	invalid.WebhookURL = "hooks.slack.com/services/secret"
+	invalid.WebhookHeaders = []string{"Authorization"}
+	invalid.WebhookTemplate = "{{.Title"
+	invalid.ExportFormats = []string{"csv", "xls"}
+	err := invalid.validate()
+	for _, msg := range []string{
+		"zen_token is required",
+		"invalid sleep_time",
+		"invalid schedule",
+		"timeout must be a positive integer, got 0",
+		"invalid notify_url: notification URL #2: scheme is missing",
+		"keep_* options must not be negative",
+		"gzip level must be in [1, 9] or 0 for default, got 42",
+		"s3_bucket is required for s3 storage",
+		"telegram_chat_id is required for telegram notifications",
+		"smtp_from and smtp_to are required for email notifications",
+		"invalid webhook_url, http(s) URL is expected",
+		`invalid header "Authorization", 'Name: value' is expected`,
+		"invalid webhook template",
+		`unknown export format "xls", one of beancount, csv,`,
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+
+	invalid = valid
+	invalid.Storage = "ftp"
+	assert.EqualError(t, invalid.validate(), `unknown storage "ftp"`)
+
+	invalid.Profiles = []string{"home", "work"}
+	invalid.profileConfig = map[string]map[string][]string{"work": {"zen_token": {""}}}
+	assert.EqualError(t, validateConfig(invalid), `profile home: unknown storage "ftp"
+profile work: zen_token is required
+profile work: unknown storage "ftp"`)
+}
+
+func TestPrintConfig(t *testing.T) {
+	opts := Opts{Token: "secret_token", SleepTime: "24h", Timeout: 10, AgeRecipients: []string{"age1one"}, WebhookStatus: []int{200, 204},
+		Profiles: []string{"home", "work"}, profileConfig: map[string]map[string][]string{
+			"home": {"zen_token": {"home_token"}, "s3_prefix": {"home"}},
+		}}
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, printConfig(opts, &buf))
+	out := buf.String()
+	for _, line := range []string{
+		"zen_token: '***'",
+		"sleep_time: 24h",
+		"timeout: 10",
+		"dedup: false",
+		"age_recipients: [age1one]",
+		"webhook_status: [200, 204]",
+		`api_token: ""`,
+		"profiles:\n  home:\n    zen_token: '***'\n    s3_prefix: home\n  work: {}",
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+	assert.NotContains(t, out, "secret_token")
+	assert.NotContains(t, out, "home_token")
+	assert.NotContains(t, out, "config:")
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/egregors/zenmoney-backup/codec"
+	log "github.com/go-pkgz/lgr"
+)
+
+// DecryptCmd is decrypt command settings.
+type DecryptCmd struct {
+	Out  string `short:"o" long:"out" description:"Output file, stdout if not set"`
+	Dir  string `long:"dir" description:"Write a file per entity type and manifest.json to the directory instead, as of split layout"`
+	Args struct {
+		File string `positional-arg-name:"FILE" description:"Encrypted or compressed backup file, e.g. zen_2024-06-29_15-30-45.json.zst.age"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func decrypt(cmd DecryptCmd, dec *codec.Decoder) (err error) {
+	if cmd.Dir != "" && cmd.Out != "" {
+		return errors.New("--out and --dir can't be used together")
+	}
+	in, err := os.Open(cmd.Args.File) // #nosec G304 - file is set by user
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+
+	// the backup is decoded as it's read, only split layout needs the whole content
+	r, err := dec.NewReader(filepath.Base(cmd.Args.File), in)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if cmd.Dir != "" {
+		plain, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return writeSplit(cmd.Dir, plain)
+	}
+	if cmd.Out == "" {
+		_, err = io.Copy(os.Stdout, r)
+		return err
+	}
+	f, err := os.OpenFile(cmd.Out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		_ = os.Remove(cmd.Out)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("[INFO] %s decrypted to %s", cmd.Args.File, cmd.Out)
+	return nil
+}
+
+// writeSplit writes a file per entity type of backup to dir.
+func writeSplit(dir string, backup []byte) error {
+	files, err := codec.SplitFiles(backup, revision)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.Name), f.Data, 0o600); err != nil {
+			return err
+		}
+	}
+	log.Printf("[INFO] %d files written to %s", len(files), dir)
+	return nil
+}
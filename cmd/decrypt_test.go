@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/egregors/zenmoney-backup/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	encoders, err := makeEncoders(Opts{Compress: "gzip", AgePassphrase: "secret"})
+	assert.NoError(t, err)
+	bs, err := codec.Encode(map[string]int{"serverTimestamp": 100}, encoders...)
+	assert.NoError(t, err)
+	in := filepath.Join(dir, "zen_2024-06-29_15-30-45.json"+codec.Ext(encoders...))
+	assert.True(t, strings.HasSuffix(in, ".json.gz.age"))
+	assert.NoError(t, os.WriteFile(in, bs, 0o600))
+
+	dec, err := makeDecoder(Opts{AgePassphrase: "secret"})
+	assert.NoError(t, err)
+
+	cmd := DecryptCmd{Out: filepath.Join(dir, "zen.json")}
+	cmd.Args.File = in
+	assert.NoError(t, decrypt(cmd, dec))
+	out, err := os.ReadFile(cmd.Out) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"serverTimestamp":100}`, string(out))
+
+	split := DecryptCmd{Dir: filepath.Join(dir, "zen")}
+	split.Args.File = in
+	assert.NoError(t, decrypt(split, dec))
+	manifest, err := os.ReadFile(filepath.Join(split.Dir, "manifest.json")) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.Contains(t, string(manifest), `"serverTimestamp": 100`)
+	split.Out = cmd.Out
+	assert.EqualError(t, decrypt(split, dec), "--out and --dir can't be used together")
+
+	dec, err = makeDecoder(Opts{})
+	assert.NoError(t, err)
+	assert.ErrorContains(t, decrypt(cmd, dec), "age identity or passphrase is required")
+
+	cmd.Args.File = filepath.Join(dir, "nope.json.age")
+	assert.Error(t, decrypt(cmd, dec))
+
+	// truncated backup fails while it's streamed, partial output is removed
+	encoders, err = makeEncoders(Opts{Compress: "gzip"})
+	assert.NoError(t, err)
+	bs, err = codec.Encode(map[string]string{"comment": strings.Repeat("lunch ", 1000)}, encoders...)
+	assert.NoError(t, err)
+	cmd.Args.File = filepath.Join(dir, "zen_2024-06-29_15-30-45.json.gz")
+	assert.NoError(t, os.WriteFile(cmd.Args.File, bs[:len(bs)-8], 0o600))
+	assert.ErrorContains(t, decrypt(cmd, dec), "unexpected EOF")
+	assert.NoFileExists(t, cmd.Out)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/codec"
+	"github.com/egregors/zenmoney-backup/export"
+	"github.com/egregors/zenmoney-backup/mirror"
+	"github.com/egregors/zenmoney-backup/snapshot"
+	"github.com/egregors/zenmoney-backup/srv"
+	log "github.com/go-pkgz/lgr"
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// ExportCmd is export command settings, subcommands are formats.
+type ExportCmd struct {
+	At   string `long:"at" description:"Export snapshot at point in time, e.g. 2024-06-29 (end of the day) or RFC3339, the latest one if not set"`
+	File string `short:"f" long:"file" description:"Export backup file instead of backups in storage, e.g. zen_2024-06-29_15-30-45.json.age"`
+	Out  string `short:"o" long:"out" description:"Output file, stdout if not set"`
+	Dir  string `long:"dir" description:"Write a file per account to the directory instead, ofx and qif only"`
+
+	CSV       struct{} `command:"csv" description:"Export transactions as CSV with names of accounts, categories and payees"`
+	Beancount struct{} `command:"beancount" description:"Export accounts and transactions as Beancount journal"`
+	Ledger    struct{} `command:"ledger" alias:"hledger" description:"Export accounts and transactions as ledger-cli and hledger journal"`
+	OFX       struct{} `command:"ofx" description:"Export bank statements of accounts as OFX 2.2"`
+	QIF       struct{} `command:"qif" description:"Export transactions of accounts as QIF"`
+	SQLite    struct{} `command:"sqlite" description:"Write all entities to SQLite database set by --out, it's updated if exists"`
+}
+
+// exporters are export formats by names of export subcommands and export_format values.
+var exporters = map[string]srv.Exporter{
+	"csv":       export.CSV{},
+	"beancount": export.Beancount{},
+	"ledger":    export.Ledger{},
+	"ofx":       export.OFX{},
+	"qif":       export.QIF{},
+}
+
+// makeExporters returns exporters of formats.
+func makeExporters(formats []string) ([]srv.Exporter, error) {
+	res := make([]srv.Exporter, 0, len(formats))
+	for _, format := range formats {
+		e, ok := exporters[format]
+		if !ok {
+			return nil, fmt.Errorf("unknown export format %q, one of %s is expected", format, strings.Join(slices.Sorted(maps.Keys(exporters)), ", "))
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func exportBackup(ctx context.Context, cmd ExportCmd, e srv.Exporter, src snapshot.Source, dec *codec.Decoder, profile string) error {
+	se, split := e.(srv.SplitExporter)
+	if cmd.Dir != "" && !split {
+		return fmt.Errorf("%s export can't be split by accounts, use --out", strings.TrimPrefix(e.Ext(), "."))
+	}
+	if cmd.Dir != "" && cmd.Out != "" {
+		return errors.New("--out and --dir can't be used together")
+	}
+
+	resp, err := loadExported(ctx, cmd, src, dec, profile)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Dir != "" {
+		if err := os.MkdirAll(cmd.Dir, 0o700); err != nil {
+			return err
+		}
+		for _, p := range se.Parts(resp) {
+			if err := writeExport(filepath.Join(cmd.Dir, p.Name+e.Ext()), p.Export); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if cmd.Out == "" {
+		return e.Export(os.Stdout, resp)
+	}
+	return writeExport(cmd.Out, func(w io.Writer) error { return e.Export(w, resp) })
+}
+
+// exportSQLite writes snapshot to SQLite database, rows missing in the snapshot are deleted from it.
+func exportSQLite(ctx context.Context, cmd ExportCmd, src snapshot.Source, dec *codec.Decoder, profile string) error {
+	if cmd.Dir != "" {
+		return errors.New("sqlite export can't be split by accounts, use --out")
+	}
+	if cmd.Out == "" {
+		return errors.New("sqlite export needs a database file, set it with --out")
+	}
+
+	resp, err := loadExported(ctx, cmd, src, dec, profile)
+	if err != nil {
+		return err
+	}
+	if err := mirror.NewSQLite(cmd.Out).Apply(ctx, resp, true); err != nil {
+		return err
+	}
+	log.Printf("[INFO] %s updated", cmd.Out)
+	return nil
+}
+
+// writeExport writes file by export function.
+func writeExport(name string, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600) // #nosec G304 - path is set by user
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Printf("[INFO] %s saved", name)
+	return nil
+}
+
+// loadExported loads backup file if it's set, or restores snapshot of profile from src.
+func loadExported(ctx context.Context, cmd ExportCmd, src snapshot.Source, dec *codec.Decoder, profile string) (models.Response, error) {
+	var resp models.Response
+	if cmd.File == "" {
+		at := time.Now()
+		if cmd.At != "" {
+			t, err := parseTime(cmd.At)
+			if err != nil {
+				return resp, err
+			}
+			at = t
+		}
+		log.Printf("[INFO] exporting snapshot at %s", at.Format(time.RFC3339))
+		return snapshot.Restore(ctx, src, profile, at)
+	}
+
+	if cmd.At != "" {
+		return resp, errors.New("--at and --file can't be used together")
+	}
+	name := filepath.Base(cmd.File)
+	if f, ok := snapshot.ParseFileName(name); ok && f.Delta {
+		return resp, fmt.Errorf("%s is a delta, export a snapshot with --at instead", name)
+	}
+	bs, err := os.ReadFile(cmd.File)
+	if err != nil {
+		return resp, err
+	}
+	plain, err := dec.Decode(name, bs)
+	if err != nil {
+		return resp, err
+	}
+	if err := json.Unmarshal(plain, &resp); err != nil {
+		return resp, fmt.Errorf("can't parse %s: %w", name, err)
+	}
+	return resp, nil
+}
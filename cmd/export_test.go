@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/egregors/zenmoney-backup/codec"
+	"github.com/egregors/zenmoney-backup/export"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportBackup(t *testing.T) {
+	src := memStore{
+		"zen_2024-06-29_10-00-00.json": []byte(`{"serverTimestamp":100,"account":[{"id":"a1","title":"Cash"}],` +
+			`"instrument":[{"id":1,"shortTitle":"USD"}],"transaction":[{"id":"tx1","date":"2024-06-28","incomeAccount":"a1",` +
+			`"outcomeAccount":"a1","outcome":5,"outcomeInstrument":1,"incomeInstrument":1,"payee":"Shop"}]}`),
+		"zen_2024-06-30_10-00-00.delta.json": []byte(`{"serverTimestamp":200,"deletion":[{"id":"tx1","object":"transaction"}]}`),
+		"zen_2024-06-29_10-00-00.csv":        []byte("exports are ignored"),
+	}
+	dec, err := makeDecoder(Opts{})
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	out := filepath.Join(dir, "zen.csv")
+	header := "date,type,account,amount,currency,to_account,to_amount,to_currency,op_amount,op_currency,category,tags,payee,comment,id\n"
+
+	assert.NoError(t, exportBackup(context.Background(), ExportCmd{At: "2024-06-29 12:00:00", Out: out}, export.CSV{}, src, dec, ""))
+	bs, err := os.ReadFile(out) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.Equal(t, header+"2024-06-28,expense,Cash,-5,USD,,,,,,,,Shop,,tx1\n", string(bs))
+
+	assert.NoError(t, exportBackup(context.Background(), ExportCmd{At: "2024-06-29 12:00:00", Out: out}, exporters["beancount"], src, dec, ""))
+	bs, err = os.ReadFile(out) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), "2024-06-28 open Assets:Other:Cash\n")
+
+	assert.NoError(t, exportBackup(context.Background(), ExportCmd{Out: out}, export.CSV{}, src, dec, ""))
+	bs, err = os.ReadFile(out) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.Equal(t, header, string(bs), "the latest snapshot has no transactions")
+
+	encoders, err := makeEncoders(Opts{Compress: "gzip"})
+	assert.NoError(t, err)
+	gz, err := codec.Encode(map[string]any{"serverTimestamp": 100}, encoders...)
+	assert.NoError(t, err)
+	file := filepath.Join(dir, "zen_2024-06-29_10-00-00.json.gz")
+	assert.NoError(t, os.WriteFile(file, gz, 0o600))
+	assert.NoError(t, exportBackup(context.Background(), ExportCmd{File: file, Out: out}, export.CSV{}, memStore{}, dec, ""))
+	bs, err = os.ReadFile(out) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.Equal(t, header, string(bs))
+
+	split := filepath.Join(dir, "statements")
+	assert.NoError(t, exportBackup(context.Background(), ExportCmd{At: "2024-06-29 12:00:00", Dir: split}, exporters["qif"], src, dec, ""))
+	bs, err = os.ReadFile(filepath.Join(split, "Cash.qif")) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), "!Account\nNCash\n")
+	err = exportBackup(context.Background(), ExportCmd{Dir: split}, export.CSV{}, src, dec, "")
+	assert.EqualError(t, err, "csv export can't be split by accounts, use --out")
+	err = exportBackup(context.Background(), ExportCmd{Dir: split, Out: out}, exporters["ofx"], src, dec, "")
+	assert.EqualError(t, err, "--out and --dir can't be used together")
+
+	err = exportBackup(context.Background(), ExportCmd{File: file, At: "2024-06-29", Out: out}, export.CSV{}, src, dec, "")
+	assert.EqualError(t, err, "--at and --file can't be used together")
+	err = exportBackup(context.Background(), ExportCmd{File: "zen_2024-06-30_10-00-00.delta.json", Out: out}, export.CSV{}, src, dec, "")
+	assert.EqualError(t, err, "zen_2024-06-30_10-00-00.delta.json is a delta, export a snapshot with --at instead")
+	err = exportBackup(context.Background(), ExportCmd{At: "2024-06-28", Out: out}, export.CSV{}, src, dec, "")
+	assert.ErrorContains(t, err, "no full backup found")
+
+	// backups of split layout are restored the same way
+	tarball, err := codec.Encode(json.RawMessage(src["zen_2024-06-29_10-00-00.json"]), codec.NewSplit("test"))
+	assert.NoError(t, err)
+	tarSrc := dec.Source(memStore{"zen_2024-06-29_10-00-00.json.tar": tarball})
+	assert.NoError(t, exportBackup(context.Background(), ExportCmd{At: "2024-06-29 12:00:00", Out: out}, export.CSV{}, tarSrc, dec, ""))
+	bs, err = os.ReadFile(out) // #nosec G304 - test file
+	assert.NoError(t, err)
+	assert.Equal(t, header+"2024-06-28,expense,Cash,-5,USD,,,,,,,,Shop,,tx1\n", string(bs))
+}
+
+func TestExportSQLite(t *testing.T) {
+	src := memStore{
+		"zen_2024-06-29_10-00-00.json": []byte(`{"serverTimestamp":100,"user":[{"id":1}],"account":[{"id":"a1","user":1,"title":"Cash"}],` +
+			`"tag":[{"id":"food","user":1,"title":"Food"}],"transaction":[{"id":"tx1","user":1,"date":"2024-06-28","incomeAccount":"a1",` +
+			`"outcomeAccount":"a1","outcome":5,"tag":["food"]}]}`),
+		"zen_2024-06-30_10-00-00.delta.json": []byte(`{"serverTimestamp":200,"deletion":[{"id":"tx1","object":"transaction"}]}`),
+	}
+	dec, err := makeDecoder(Opts{})
+	assert.NoError(t, err)
+	out := filepath.Join(t.TempDir(), "zen.db")
+
+	assert.NoError(t, exportSQLite(context.Background(), ExportCmd{At: "2024-06-29 12:00:00", Out: out}, src, dec, ""))
+	db, err := sql.Open("sqlite3", out)
+	assert.NoError(t, err)
+	defer db.Close()
+	var title string
+	assert.NoError(t, db.QueryRow(`SELECT t.title FROM transaction_tags tt JOIN tags t ON t.id = tt.tag_id WHERE tt.transaction_id = 'tx1'`).Scan(&title))
+	assert.Equal(t, "Food", title)
+
+	assert.NoError(t, exportSQLite(context.Background(), ExportCmd{Out: out}, src, dec, ""))
+	var n int
+	assert.NoError(t, db.QueryRow(`SELECT count(*) FROM transactions`).Scan(&n))
+	assert.Equal(t, 0, n, "the latest snapshot has no transactions")
+
+	err = exportSQLite(context.Background(), ExportCmd{}, src, dec, "")
+	assert.EqualError(t, err, "sqlite export needs a database file, set it with --out")
+	err = exportSQLite(context.Background(), ExportCmd{Dir: "statements"}, src, dec, "")
+	assert.EqualError(t, err, "sqlite export can't be split by accounts, use --out")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthcheck(t *testing.T) {
+	ready := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if !ready {
+			http.Error(w, "no successful backup yet", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	}))
+	defer ts.Close()
+
+	err := healthcheck(HealthcheckCmd{URL: ts.URL})
+	assert.EqualError(t, err, "503 Service Unavailable: no successful backup yet")
+
+	ready = true
+	assert.NoError(t, healthcheck(HealthcheckCmd{URL: ts.URL}))
+
+	ts.Close()
+	assert.Error(t, healthcheck(HealthcheckCmd{URL: ts.URL}))
+}
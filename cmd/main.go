@@ -5,35 +5,124 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/egregors/zenmoney-backup/codec"
+	"github.com/egregors/zenmoney-backup/mirror"
 	"github.com/egregors/zenmoney-backup/notifier"
+	"github.com/egregors/zenmoney-backup/retention"
 	"github.com/egregors/zenmoney-backup/srv"
 	"github.com/egregors/zenmoney-backup/store"
 	log "github.com/go-pkgz/lgr"
 	"github.com/jessevdk/go-flags"
+	"github.com/robfig/cron/v3"
 )
 
 // Opts is App settings (from cli args or ENV).
 type Opts struct {
-	Token     string `short:"t" long:"zenmoney OAuth token" env:"ZEN_TOKEN" description:"Zenmoney API Token, to get it visit: https://zerro.app/token"`
-	SleepTime string `short:"p" long:"sleep_time" env:"SLEEP_TIME" default:"24h" description:"Backup performs every SLEEP_TIME minutes"`
-	Timeout   int    `short:"c" long:"timeout" env:"TIMEOUT" default:"10" description:"Backup request timeout in seconds"`
-	NotifyURL string `short:"n" long:"notify_url" env:"NOTIFY_URL" description:"ntfy.sh notification URL (e.g., https://ntfy.sh/your_topic)"`
+	ConfigFile string `long:"config" env:"CONFIG" description:"YAML or TOML config file, flags and env take precedence over it"`
+
+	Token     string   `short:"t" long:"zenmoney OAuth token" env:"ZEN_TOKEN" secret:"true" description:"Zenmoney API Token, to get it visit: https://zerro.app/token"`
+	SleepTime string   `short:"p" long:"sleep_time" env:"SLEEP_TIME" default:"24h" description:"Backup performs every SLEEP_TIME minutes"`
+	Schedule  string   `long:"schedule" env:"SCHEDULE" description:"Cron expression (5 or 6 fields, optional CRON_TZ=<zone> prefix) used instead of SLEEP_TIME"`
+	Once      bool     `long:"once" env:"ONCE" description:"Make a single backup and exit, same as backup command"`
+	Timeout   int      `short:"c" long:"timeout" env:"TIMEOUT" default:"10" description:"Backup request timeout in seconds"`
+	NotifyURL []string `short:"n" long:"notify_url" env:"NOTIFY_URL" env-delim:" " secret:"true" description:"Notification URL (e.g., ntfy://your_topic, tgram://bot_token/chat_id or https://ntfy.sh/your_topic), can be repeated"`
+
+	TelegramToken    string `long:"telegram_token" env:"TELEGRAM_TOKEN" secret:"true" description:"Telegram bot token, enables Telegram notifications"`
+	TelegramChatID   string `long:"telegram_chat_id" env:"TELEGRAM_CHAT_ID" description:"Telegram chat ID (e.g., -1001234567890) or @channel name"`
+	TelegramThreadID int    `long:"telegram_thread_id" env:"TELEGRAM_THREAD_ID" description:"Telegram forum topic ID"`
+	TelegramSilent   bool   `long:"telegram_silent" env:"TELEGRAM_SILENT" description:"Send Telegram messages without sound"`
+	TelegramAPIURL   string `long:"telegram_api_url" env:"TELEGRAM_API_URL" default:"https://api.telegram.org" description:"Telegram Bot API base URL"`
+
+	SMTPHost     string   `long:"smtp_host" env:"SMTP_HOST" description:"SMTP server host, enables email notifications"`
+	SMTPPort     int      `long:"smtp_port" env:"SMTP_PORT" default:"587" description:"SMTP server port"`
+	SMTPSecurity string   `long:"smtp_security" env:"SMTP_SECURITY" default:"starttls" choice:"starttls" choice:"tls" choice:"none" description:"SMTP connection security"`
+	SMTPUsername string   `long:"smtp_username" env:"SMTP_USERNAME" description:"SMTP username, authentication is skipped if empty"`
+	SMTPPassword string   `long:"smtp_password" env:"SMTP_PASSWORD" secret:"true" description:"SMTP password"`
+	SMTPFrom     string   `long:"smtp_from" env:"SMTP_FROM" description:"Sender email address"`
+	SMTPTo       []string `long:"smtp_to" env:"SMTP_TO" env-delim:"," description:"Recipient email address, can be repeated"`
+	SMTPReport   bool     `long:"smtp_report" env:"SMTP_REPORT" description:"Send weekly HTML report of backup runs by email"`
+
+	WebhookURL      string   `long:"webhook_url" env:"WEBHOOK_URL" secret:"true" description:"URL of HTTP endpoint, enables webhook notifications"`
+	WebhookMethod   string   `long:"webhook_method" env:"WEBHOOK_METHOD" default:"POST" description:"HTTP method of webhook request"`
+	WebhookHeaders  []string `long:"webhook_header" env:"WEBHOOK_HEADERS" env-delim:"\n" description:"Header of webhook request as 'Name: value', can be repeated"`
+	WebhookTemplate string   `long:"webhook_template" env:"WEBHOOK_TEMPLATE" description:"Go text/template of webhook body with .Title and .Message, JSON object with title and message if empty"`
+	WebhookStatus   []int    `long:"webhook_status" env:"WEBHOOK_STATUS" env-delim:"," description:"Expected status code of webhook response, any 2xx if not set, can be repeated"`
+
+	Profiles []string `long:"profile" env:"PROFILES" env-delim:"," description:"Back up several accounts, settings of a profile are overridden by <PROFILE>_<ENV> variables, e.g. HOME_ZEN_TOKEN, can be repeated"`
+
+	Listen      string `long:"listen" env:"LISTEN" description:"Address of HTTP server with health and status endpoints, e.g. :8080"`
+	ReadyMaxAge string `long:"ready_max_age" env:"READY_MAX_AGE" default:"48h" description:"/readyz fails if there was no successful backup for READY_MAX_AGE, 0 disables the check"`
+	APIToken    string `long:"api_token" env:"API_TOKEN" secret:"true" description:"Bearer token of POST /backup endpoint triggering a backup, the endpoint is disabled if not set"`
+
+	RetryAttempts int    `long:"retry_attempts" env:"RETRY_ATTEMPTS" default:"3" description:"Attempts to make a backup if it fails with a temporary error, 1 disables retries"`
+	RetryDelay    string `long:"retry_delay" env:"RETRY_DELAY" default:"1m" description:"Delay before the first retry, doubled for every next one"`
+	RetryMaxDelay string `long:"retry_max_delay" env:"RETRY_MAX_DELAY" default:"30m" description:"Maximal delay between retries"`
+
+	Incremental bool   `long:"incremental" env:"INCREMENTAL" description:"Save only changes since the previous backup as delta files"`
+	FullEvery   string `long:"full_every" env:"FULL_EVERY" default:"168h" description:"Make a full backup every FULL_EVERY in incremental mode"`
+	Dedup       bool   `long:"dedup" env:"DEDUP" description:"Skip backups identical to the previous one, and empty deltas in incremental mode"`
+
+	ExportFormats []string `long:"export_format" env:"EXPORT_FORMATS" env-delim:"," description:"Save export of every full backup in the format next to it, csv, beancount, ledger, ofx or qif, can be repeated"`
+	SQLiteMirror  string   `long:"sqlite_mirror" env:"SQLITE_MIRROR" description:"SQLite database updated by every backup, e.g. /data/zen.db, for SQL queries and Grafana"`
+
+	KeepLast    int  `long:"keep_last" env:"KEEP_LAST" description:"Retention: keep N most recent backups"`
+	KeepDaily   int  `long:"keep_daily" env:"KEEP_DAILY" description:"Retention: keep the last backup of every day for D days"`
+	KeepWeekly  int  `long:"keep_weekly" env:"KEEP_WEEKLY" description:"Retention: keep the last backup of every week for W weeks"`
+	KeepMonthly int  `long:"keep_monthly" env:"KEEP_MONTHLY" description:"Retention: keep the last backup of every month for M months"`
+	KeepYearly  bool `long:"keep_yearly" env:"KEEP_YEARLY" description:"Retention: keep the last backup of every year forever"`
+	PruneDryRun bool `long:"prune_dry_run" env:"PRUNE_DRY_RUN" description:"Retention: only log backups which would be deleted"`
+
+	Layout        string `long:"layout" env:"LAYOUT" default:"single" choice:"single" choice:"split" description:"Backup layout, a JSON file or tar archive with a file per entity type and manifest"`
+	Compress      string `long:"compress" env:"COMPRESS" default:"none" choice:"none" choice:"gzip" choice:"zstd" description:"Backup compression"`
+	CompressLevel int    `long:"compress_level" env:"COMPRESS_LEVEL" description:"Compression level, gzip 1-9, zstd 1-22, default level of the algorithm if not set"`
+
+	AgeRecipients []string `long:"age_recipient" env:"AGE_RECIPIENTS" env-delim:"," description:"Encrypt backups to age public key (age1...), can be repeated"`
+	AgePassphrase string   `long:"age_passphrase" env:"AGE_PASSPHRASE" secret:"true" description:"Encrypt backups with age passphrase"`
+	AgeIdentity   string   `long:"age_identity" env:"AGE_IDENTITY" description:"age identity file (AGE-SECRET-KEY-1...) to read encrypted backups"`
+
+	Storage     string `long:"storage" env:"STORAGE" default:"local" choice:"local" choice:"s3" description:"Backup storage backend"`
+	S3Endpoint  string `long:"s3_endpoint" env:"S3_ENDPOINT" description:"S3 endpoint URL (e.g., http://localhost:9000), AWS endpoint for the region if empty"`
+	S3Bucket    string `long:"s3_bucket" env:"S3_BUCKET" description:"S3 bucket name"`
+	S3Prefix    string `long:"s3_prefix" env:"S3_PREFIX" description:"S3 key prefix for backup files"`
+	S3Region    string `long:"s3_region" env:"S3_REGION" default:"us-east-1" description:"S3 region"`
+	S3AccessKey string `long:"s3_access_key" env:"S3_ACCESS_KEY" secret:"true" description:"S3 access key ID"`
+	S3SecretKey string `long:"s3_secret_key" env:"S3_SECRET_KEY" secret:"true" description:"S3 secret access key"`
+	S3PathStyle bool   `long:"s3_path_style" env:"S3_PATH_STYLE" description:"Use path-style S3 URLs (required by MinIO and some other providers)"`
 
 	Dbg bool `long:"dbg" env:"DEBUG" description:"Debug mode"`
+
+	Backup          BackupCmd          `command:"backup" description:"Make a single backup, apply retention and exit"`
+	RestoreSnapshot RestoreSnapshotCmd `command:"restore-snapshot" description:"Rebuild full backup for a point in time from a full backup and following deltas"`
+	Prune           PruneCmd           `command:"prune" description:"Delete old backups according to retention settings"`
+	Decrypt         DecryptCmd         `command:"decrypt" alias:"decode" description:"Decrypt and decompress backup file to plain JSON"`
+	Healthcheck     HealthcheckCmd     `command:"healthcheck" description:"Check HTTP server of a running instance, for Docker HEALTHCHECK"`
+	Config          ConfigCmd          `command:"config" description:"Validate or print settings"`
+	Export          ExportCmd          `command:"export" description:"Export backup to other formats"`
+
+	profileConfig map[string]map[string][]string // settings of profiles from config file
+	configErr     error                          // reported by config validate
+	command       string                         // active command, only secrets it uses are resolved
 }
 
 var revision = "unknown"
 
+// reportPeriod is how often the email report and digests of backup runs are sent.
+const reportPeriod = 7 * 24 * time.Hour
+
 func main() {
-	fmt.Printf("zenmoney-backup %s\n~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=[,,_,,]:3\n", revision)
+	fmt.Fprintf(os.Stderr, "zenmoney-backup %s\n~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=~=[,,_,,]:3\n", revision)
 
 	var opts Opts
 	p := flags.NewParser(&opts, flags.PrintErrors|flags.PassDoubleDash|flags.HelpFlag)
+	p.SubcommandsOptional = true
 	if _, err := p.Parse(); err != nil {
 		var flagsErr *flags.Error
 		if errors.As(err, &flagsErr) && flagsErr.Type != flags.ErrHelp {
@@ -42,12 +131,13 @@ func main() {
 		os.Exit(2)
 	}
 
-	setupLog(opts.Dbg)
-
-	s, err := makeServer(opts)
-	if err != nil {
-		log.Printf("[FATAL] can't make server: %s", err)
-		os.Exit(1)
+	command := commandName(p)
+	if err := loadSettings(p, &opts); err != nil {
+		opts.configErr = err
+		if command != "config validate" {
+			log.Printf("[ERROR] invalid settings: %s", err)
+			os.Exit(exitError)
+		}
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -61,18 +151,188 @@ func main() {
 		log.Printf("[INFO] shutting down")
 	}(cancel)
 
-	s.Run(ctx)
+	if command == "" && opts.Once {
+		setupLog(opts.Dbg, os.Stdout)
+		if err := runCommand(ctx, "backup", opts); err != nil {
+			log.Printf("[ERROR] backup failed: %s", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	if command != "" {
+		// commands may write results to stdout, keep it clean
+		setupLog(opts.Dbg, os.Stderr)
+		if err := runCommand(ctx, command, opts); err != nil {
+			log.Printf("[ERROR] %s failed: %s", command, err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	setupLog(opts.Dbg, os.Stdout)
+
+	servers, err := makeServers(opts)
+	if err != nil {
+		log.Printf("[FATAL] can't make server: %s", err)
+		os.Exit(1)
+	}
+
+	if opts.Listen != "" {
+		httpOpts, err := opts.http()
+		if err != nil {
+			log.Printf("[FATAL] can't make http server: %s", err)
+			os.Exit(1)
+		}
+		go func() {
+			if err := srv.ListenAndServe(ctx, httpOpts, servers...); err != nil {
+				log.Printf("[ERROR] http server failed: %s", err)
+			}
+		}()
+	}
+
+	go triggerOnSignal(servers)
+
+	// profiles run independently, a failing one doesn't affect others
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Go(func() { s.Run(ctx) })
+	}
+	wg.Wait()
+}
+
+// loadSettings completes options parsed from flags and env by config file and resolves secrets
+// used by the active command.
+func loadSettings(p *flags.Parser, opts *Opts) error {
+	opts.command = commandName(p)
+	var errs []error
+	if opts.ConfigFile != "" {
+		errs = append(errs, applyConfig(p, opts))
+	}
+	errs = append(errs, applySecretFiles(p, opts), opts.resolveSecrets())
+	return errors.Join(errs...)
 }
 
-func setupLog(dbg bool) {
+// commandName returns name of the active command including subcommands, e.g. "config validate".
+func commandName(p *flags.Parser) string {
+	var names []string
+	for c := p.Active; c != nil; c = c.Active {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func setupLog(dbg bool, out io.Writer) {
 	if dbg {
-		log.Setup(log.Debug, log.CallerFile, log.CallerFunc, log.Msec, log.LevelBraces)
+		log.Setup(log.Debug, log.CallerFile, log.CallerFunc, log.Msec, log.LevelBraces, log.Out(out))
 		return
 	}
-	log.Setup(log.Msec, log.LevelBraces)
+	log.Setup(log.Msec, log.LevelBraces, log.Out(out))
 }
 
-func makeServer(opts Opts) (*srv.Server, error) {
+func runCommand(ctx context.Context, name string, opts Opts) error {
+	switch name {
+	case "backup":
+		servers, err := makeServers(opts)
+		if err != nil {
+			return err
+		}
+		return backupAll(ctx, servers)
+	case "restore-snapshot":
+		profiles, err := opts.profiles()
+		if err != nil {
+			return err
+		}
+		if len(profiles) > 1 {
+			return errors.New("restore-snapshot works with a single profile, select it with --profile")
+		}
+		p := profiles[0]
+		st, err := makeStore(p.opts)
+		if err != nil {
+			return err
+		}
+		dec, err := makeDecoder(p.opts)
+		if err != nil {
+			return err
+		}
+		return restoreSnapshot(ctx, opts.RestoreSnapshot, dec.Source(st), p.name)
+	case "export csv", "export beancount", "export ledger", "export ofx", "export qif", "export sqlite":
+		profiles, err := opts.profiles()
+		if err != nil {
+			return err
+		}
+		if len(profiles) > 1 {
+			return errors.New("export works with a single profile, select it with --profile")
+		}
+		p := profiles[0]
+		st, err := makeStore(p.opts)
+		if err != nil {
+			return err
+		}
+		dec, err := makeDecoder(p.opts)
+		if err != nil {
+			return err
+		}
+		if name == "export sqlite" {
+			return exportSQLite(ctx, opts.Export, dec.Source(st), dec, p.name)
+		}
+		return exportBackup(ctx, opts.Export, exporters[strings.TrimPrefix(name, "export ")], dec.Source(st), dec, p.name)
+	case "prune":
+		profiles, err := opts.profiles()
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, p := range profiles {
+			s, err := makeServer(p.opts, p.name)
+			if err != nil {
+				return err
+			}
+			cmd := PruneCmd{DryRun: opts.Prune.DryRun || p.opts.PruneDryRun}
+			if err := prune(ctx, cmd, s, p.opts.retention(), os.Stdout); err != nil {
+				errs = append(errs, withProfile(p.name, err))
+			}
+		}
+		return errors.Join(errs...)
+	case "healthcheck":
+		return healthcheck(opts.Healthcheck)
+	case "decrypt":
+		dec, err := makeDecoder(opts)
+		if err != nil {
+			return err
+		}
+		return decrypt(opts.Decrypt, dec)
+	case "config validate":
+		if err := validateConfig(opts); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(os.Stdout, "config is valid")
+		return err
+	case "config print":
+		return printConfig(opts, os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// makeServers makes server of every profile.
+func makeServers(opts Opts) ([]*srv.Server, error) {
+	profiles, err := opts.profiles()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*srv.Server, 0, len(profiles))
+	for _, p := range profiles {
+		s, err := makeServer(p.opts, p.name)
+		if err != nil {
+			return nil, withProfile(p.name, err)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func makeServer(opts Opts, profile string) (*srv.Server, error) {
 	d, err := time.ParseDuration(opts.SleepTime)
 	if err != nil {
 		return nil, err
@@ -84,14 +344,261 @@ func makeServer(opts Opts) (*srv.Server, error) {
 	
 	timeout := time.Duration(opts.Timeout) * time.Second
 	
-	// Create notifier
-	var n srv.Notifier
-	if opts.NotifyURL != "" {
-		n = notifier.NewNtfy(opts.NotifyURL)
-		log.Printf("[INFO] notifications enabled for URL: %s", opts.NotifyURL)
-	} else {
-		n = notifier.NewNoop()
+	n, err := makeNotifier(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := makeStore(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var srvOpts []srv.Option
+	if opts.Schedule != "" {
+		sched, err := makeSchedule(opts.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		srvOpts = append(srvOpts, srv.WithSchedule(sched))
+		log.Printf("[INFO] backups are scheduled by %q", opts.Schedule)
+	}
+	if opts.RetryAttempts > 1 {
+		p, err := opts.retry()
+		if err != nil {
+			return nil, err
+		}
+		srvOpts = append(srvOpts, srv.WithRetry(p))
+	}
+	if opts.Incremental {
+		fullEvery, err := time.ParseDuration(opts.FullEvery)
+		if err != nil {
+			return nil, fmt.Errorf("invalid full_every: %w", err)
+		}
+		srvOpts = append(srvOpts, srv.WithIncremental(fullEvery))
+		log.Printf("[INFO] incremental backups enabled, full backup every %s", fullEvery)
+	}
+	if opts.Dedup {
+		srvOpts = append(srvOpts, srv.WithDedup())
+		log.Printf("[INFO] unchanged backups are skipped")
+	}
+	if opts.Layout == "split" {
+		srvOpts = append(srvOpts, srv.WithLayout(codec.NewSplit(revision)))
+		log.Printf("[INFO] backups are split by entity types")
+	}
+	encoders, err := makeEncoders(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(encoders) > 0 {
+		srvOpts = append(srvOpts, srv.WithEncoders(encoders...))
+	}
+	if len(opts.ExportFormats) > 0 {
+		exps, err := makeExporters(opts.ExportFormats)
+		if err != nil {
+			return nil, err
+		}
+		srvOpts = append(srvOpts, srv.WithExporters(exps...))
+		log.Printf("[INFO] full backups are exported to %s", strings.Join(opts.ExportFormats, ", "))
+	}
+	if opts.SQLiteMirror != "" {
+		srvOpts = append(srvOpts, srv.WithMirror(mirror.NewSQLite(opts.SQLiteMirror)))
+		log.Printf("[INFO] backups are mirrored to %s", opts.SQLiteMirror)
+	}
+	if p := opts.retention(); p.Enabled() {
+		srvOpts = append(srvOpts, srv.WithRetention(p, opts.PruneDryRun))
+		log.Printf("[INFO] retention enabled: %s", p)
+	}
+	if profile != "" {
+		srvOpts = append(srvOpts, srv.WithProfile(profile))
+	}
+
+	return srv.NewServer(opts.Token, d, timeout, storage, n, srvOpts...), nil
+}
+
+// makeNotifier makes notifier sending to all configured services.
+func makeNotifier(opts Opts) (srv.Notifier, error) {
+	var res notifier.Multi
+	if len(opts.NotifyURL) > 0 {
+		router, err := notifier.NewRouter(opts.NotifyURL, reportPeriod)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, router)
+		log.Printf("[INFO] notifications enabled for %d URL(s)", len(opts.NotifyURL))
+	}
+	if opts.TelegramToken != "" {
+		if opts.TelegramChatID == "" {
+			return nil, errors.New("telegram_chat_id is required for telegram notifications")
+		}
+		res = append(res, notifier.NewTelegram(notifier.TelegramOpts{
+			Token:    opts.TelegramToken,
+			ChatID:   opts.TelegramChatID,
+			ThreadID: opts.TelegramThreadID,
+			Silent:   opts.TelegramSilent,
+			APIURL:   opts.TelegramAPIURL,
+		}))
+		log.Printf("[INFO] telegram notifications enabled for chat %s", opts.TelegramChatID)
+	}
+	if opts.SMTPHost != "" {
+		if opts.SMTPFrom == "" || len(opts.SMTPTo) == 0 {
+			return nil, errors.New("smtp_from and smtp_to are required for email notifications")
+		}
+		email := notifier.NewEmail(notifier.EmailOpts{
+			Host:     opts.SMTPHost,
+			Port:     opts.SMTPPort,
+			Security: opts.SMTPSecurity,
+			Username: opts.SMTPUsername,
+			Password: opts.SMTPPassword,
+			From:     opts.SMTPFrom,
+			To:       opts.SMTPTo,
+		})
+		res = append(res, email)
+		if opts.SMTPReport {
+			res = append(res, notifier.NewReport(email, reportPeriod))
+		}
+		log.Printf("[INFO] email notifications enabled for %s, weekly report: %t", strings.Join(opts.SMTPTo, ", "), opts.SMTPReport)
+	}
+	if opts.WebhookURL != "" {
+		headers, err := parseHeaders(opts.WebhookHeaders)
+		if err != nil {
+			return nil, err
+		}
+		webhook, err := notifier.NewWebhook(notifier.WebhookOpts{
+			URL:         opts.WebhookURL,
+			Method:      opts.WebhookMethod,
+			Headers:     headers,
+			Template:    opts.WebhookTemplate,
+			StatusCodes: opts.WebhookStatus,
+		})
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, webhook)
+		log.Printf("[INFO] webhook notifications enabled")
+	}
+
+	switch len(res) {
+	case 0:
+		return notifier.NewNoop(), nil
+	case 1:
+		return res[0], nil
+	default:
+		return res, nil
+	}
+}
+
+// parseHeaders parses HTTP headers in "Name: value" format.
+func parseHeaders(headers []string) (map[string]string, error) {
+	res := make(map[string]string, len(headers))
+	for _, h := range headers {
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, 'Name: value' is expected", h)
+		}
+		res[name] = strings.TrimSpace(value)
+	}
+	return res, nil
+}
+
+// makeSchedule parses standard cron expression with optional seconds field and CRON_TZ prefix.
+func makeSchedule(spec string) (srv.Schedule, error) {
+	p := cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	sched, err := p.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return sched, nil
+}
+
+// makeEncoders makes backup pipeline stages. Compression goes first, as encrypted data can't be compressed.
+func makeEncoders(opts Opts) ([]codec.Encoder, error) {
+	var res []codec.Encoder
+	if opts.Compress != "" && opts.Compress != "none" {
+		c, err := codec.NewCompressor(opts.Compress, opts.CompressLevel)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+		log.Printf("[INFO] backups are compressed with %s", opts.Compress)
+	}
+	if len(opts.AgeRecipients) > 0 || opts.AgePassphrase != "" {
+		a, err := codec.NewAge(opts.AgeRecipients, opts.AgePassphrase)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+		log.Printf("[INFO] backups are encrypted with age")
+	}
+	return res, nil
+}
+
+// makeDecoder makes decoder of stored backups, able to decrypt them if age identity or passphrase is set.
+func makeDecoder(opts Opts) (*codec.Decoder, error) {
+	if opts.AgeIdentity == "" && opts.AgePassphrase == "" {
+		return codec.NewDecoder(nil), nil
+	}
+	a, err := codec.NewAgeDecrypter(opts.AgeIdentity, opts.AgePassphrase)
+	if err != nil {
+		return nil, err
+	}
+	return codec.NewDecoder(a), nil
+}
+
+// http returns settings of HTTP server.
+func (opts Opts) http() (srv.HTTPOpts, error) {
+	maxAge, err := time.ParseDuration(opts.ReadyMaxAge)
+	if err != nil {
+		return srv.HTTPOpts{}, fmt.Errorf("invalid ready_max_age: %w", err)
+	}
+	return srv.HTTPOpts{Addr: opts.Listen, ReadyMaxAge: maxAge, APIToken: opts.APIToken}, nil
+}
+
+// retry returns retry policy of failed backups.
+func (opts Opts) retry() (srv.RetryPolicy, error) {
+	delay, err := time.ParseDuration(opts.RetryDelay)
+	if err != nil {
+		return srv.RetryPolicy{}, fmt.Errorf("invalid retry_delay: %w", err)
+	}
+	maxDelay, err := time.ParseDuration(opts.RetryMaxDelay)
+	if err != nil {
+		return srv.RetryPolicy{}, fmt.Errorf("invalid retry_max_delay: %w", err)
+	}
+	return srv.RetryPolicy{MaxAttempts: opts.RetryAttempts, Delay: delay, MaxDelay: maxDelay}, nil
+}
+
+// retention returns backups retention policy.
+func (opts Opts) retention() retention.Policy {
+	return retention.Policy{
+		KeepLast:    opts.KeepLast,
+		KeepDaily:   opts.KeepDaily,
+		KeepWeekly:  opts.KeepWeekly,
+		KeepMonthly: opts.KeepMonthly,
+		KeepYearly:  opts.KeepYearly,
+	}
+}
+
+func makeStore(opts Opts) (srv.Saver, error) {
+	switch opts.Storage {
+	case "", "local":
+		return store.LocalFs{}, nil
+	case "s3":
+		s, err := store.NewS3(store.S3Opts{
+			Endpoint:  opts.S3Endpoint,
+			Bucket:    opts.S3Bucket,
+			Prefix:    opts.S3Prefix,
+			Region:    opts.S3Region,
+			AccessKey: opts.S3AccessKey,
+			SecretKey: opts.S3SecretKey,
+			PathStyle: opts.S3PathStyle,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[INFO] backups are stored in s3 bucket %s", opts.S3Bucket)
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown storage %q", opts.Storage)
 	}
-	
-	return srv.NewServer(opts.Token, d, timeout, store.LocalFs{}, n), nil
 }
@@ -2,6 +2,10 @@ package main
 
 import (
 	"testing"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/notifier"
+	"github.com/egregors/zenmoney-backup/srv"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,11 +54,198 @@ func TestMakeServer(t *testing.T) {
 			},
 			shouldError: true,
 		},
+		{
+			name: "incremental",
+			opts: Opts{
+				Token:       "test_token",
+				SleepTime:   "1h",
+				Timeout:     10,
+				Incremental: true,
+				FullEvery:   "168h",
+			},
+			shouldError: false,
+		},
+		{
+			name: "dedup",
+			opts: Opts{
+				Token:     "test_token",
+				SleepTime: "1h",
+				Timeout:   10,
+				Dedup:     true,
+			},
+			shouldError: false,
+		},
+		{
+			name: "export formats",
+			opts: Opts{
+				Token:         "test_token",
+				SleepTime:     "1h",
+				Timeout:       10,
+				ExportFormats: []string{"csv", "beancount", "ledger"},
+			},
+			shouldError: false,
+		},
+		{
+			name: "split layout",
+			opts: Opts{
+				Token:     "test_token",
+				SleepTime: "1h",
+				Timeout:   10,
+				Layout:    "split",
+			},
+			shouldError: false,
+		},
+		{
+			name: "sqlite mirror",
+			opts: Opts{
+				Token:        "test_token",
+				SleepTime:    "1h",
+				Timeout:      10,
+				SQLiteMirror: "zen.db",
+			},
+			shouldError: false,
+		},
+		{
+			name: "incremental with invalid full_every",
+			opts: Opts{
+				Token:       "test_token",
+				SleepTime:   "1h",
+				Timeout:     10,
+				Incremental: true,
+				FullEvery:   "weekly",
+			},
+			shouldError: true,
+			errorMsg:    "invalid full_every",
+		},
+		{
+			name: "zstd compression",
+			opts: Opts{
+				Token:         "test_token",
+				SleepTime:     "24h",
+				Timeout:       10,
+				Compress:      "zstd",
+				CompressLevel: 19,
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid gzip level",
+			opts: Opts{
+				Token:         "test_token",
+				SleepTime:     "24h",
+				Timeout:       10,
+				Compress:      "gzip",
+				CompressLevel: 19,
+			},
+			shouldError: true,
+			errorMsg:    "gzip level must be in [1, 9] or 0 for default, got 19",
+		},
+		{
+			name: "age passphrase",
+			opts: Opts{
+				Token:         "test_token",
+				SleepTime:     "24h",
+				Timeout:       10,
+				AgePassphrase: "secret",
+			},
+			shouldError: false,
+		},
+		{
+			name: "age passphrase with recipients",
+			opts: Opts{
+				Token:         "test_token",
+				SleepTime:     "24h",
+				Timeout:       10,
+				AgePassphrase: "secret",
+				AgeRecipients: []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"},
+			},
+			shouldError: true,
+			errorMsg:    "can't be combined with recipients",
+		},
+		{
+			name: "s3 storage",
+			opts: Opts{
+				Token:     "test_token",
+				SleepTime: "24h",
+				Timeout:   10,
+				Storage:   "s3",
+				S3Bucket:  "backups",
+			},
+			shouldError: false,
+		},
+		{
+			name: "s3 storage without bucket",
+			opts: Opts{
+				Token:     "test_token",
+				SleepTime: "24h",
+				Timeout:   10,
+				Storage:   "s3",
+			},
+			shouldError: true,
+			errorMsg:    "s3 bucket is required",
+		},
+		{
+			name: "schedule",
+			opts: Opts{
+				Token:     "test_token",
+				SleepTime: "24h",
+				Timeout:   10,
+				Schedule:  "0 3 * * *",
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid schedule",
+			opts: Opts{
+				Token:     "test_token",
+				SleepTime: "24h",
+				Timeout:   10,
+				Schedule:  "at 3am",
+			},
+			shouldError: true,
+			errorMsg:    "invalid schedule",
+		},
+		{
+			name: "retry",
+			opts: Opts{
+				Token:         "test_token",
+				SleepTime:     "24h",
+				Timeout:       10,
+				RetryAttempts: 3,
+				RetryDelay:    "1m",
+				RetryMaxDelay: "30m",
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid retry delay",
+			opts: Opts{
+				Token:         "test_token",
+				SleepTime:     "24h",
+				Timeout:       10,
+				RetryAttempts: 3,
+				RetryDelay:    "soon",
+				RetryMaxDelay: "30m",
+			},
+			shouldError: true,
+			errorMsg:    "invalid retry_delay",
+		},
+		{
+			name: "unknown storage",
+			opts: Opts{
+				Token:     "test_token",
+				SleepTime: "24h",
+				Timeout:   10,
+				Storage:   "ftp",
+			},
+			shouldError: true,
+			errorMsg:    "unknown storage",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			server, err := makeServer(tt.opts)
+			server, err := makeServer(tt.opts, "")
 			
 			if tt.shouldError {
 				assert.Error(t, err)
@@ -68,4 +259,101 @@ func TestMakeServer(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestMakeNotifier(t *testing.T) {
+	n, err := makeNotifier(Opts{})
+	assert.NoError(t, err)
+	assert.IsType(t, notifier.Noop{}, n)
+
+	n, err = makeNotifier(Opts{TelegramToken: "123:abc", TelegramChatID: "42"})
+	assert.NoError(t, err)
+	assert.IsType(t, &notifier.Telegram{}, n)
+
+	n, err = makeNotifier(Opts{NotifyURL: []string{"https://ntfy.sh/topic"}, TelegramToken: "123:abc", TelegramChatID: "42"})
+	assert.NoError(t, err)
+	assert.Len(t, n, 2)
+
+	_, err = makeNotifier(Opts{TelegramToken: "123:abc"})
+	assert.EqualError(t, err, "telegram_chat_id is required for telegram notifications")
+
+	n, err = makeNotifier(Opts{SMTPHost: "smtp.example.com", SMTPFrom: "zenb@example.com", SMTPTo: []string{"me@example.com"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &notifier.Email{}, n)
+
+	n, err = makeNotifier(Opts{SMTPHost: "smtp.example.com", SMTPFrom: "zenb@example.com", SMTPTo: []string{"me@example.com"}, SMTPReport: true})
+	assert.NoError(t, err)
+	if multi, ok := n.(notifier.Multi); assert.True(t, ok) && assert.Len(t, multi, 2) {
+		assert.IsType(t, &notifier.Email{}, multi[0])
+		assert.IsType(t, &notifier.Report{}, multi[1])
+	}
+
+	_, err = makeNotifier(Opts{SMTPHost: "smtp.example.com"})
+	assert.EqualError(t, err, "smtp_from and smtp_to are required for email notifications")
+
+	n, err = makeNotifier(Opts{WebhookURL: "https://example.com/hook", WebhookHeaders: []string{"Authorization: Bearer token"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &notifier.Webhook{}, n)
+
+	n, err = makeNotifier(Opts{NotifyURL: []string{"ntfy://topic", "tgram://123:abc/42?levels=error,success"}})
+	assert.NoError(t, err)
+	assert.IsType(t, &notifier.Router{}, n)
+
+	_, err = makeNotifier(Opts{NotifyURL: []string{"ntfy://topic?levels=all"}})
+	assert.EqualError(t, err, `notification URL #1: unknown level "all", error, success or digest is expected`)
+
+	_, err = makeNotifier(Opts{WebhookURL: "https://example.com/hook", WebhookTemplate: "{{.Title"})
+	assert.ErrorContains(t, err, "invalid webhook template")
+}
+
+func TestParseHeaders(t *testing.T) {
+	res, err := parseHeaders([]string{"Authorization: Bearer token", "X-Priority:5", "Content-Type: application/json; charset=utf-8"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Authorization": "Bearer token",
+		"X-Priority":    "5",
+		"Content-Type":  "application/json; charset=utf-8",
+	}, res)
+
+	_, err = parseHeaders([]string{": value"})
+	assert.EqualError(t, err, `invalid header ": value", 'Name: value' is expected`)
+}
+
+func TestMakeSchedule(t *testing.T) {
+	msk, err := time.LoadLocation("Europe/Moscow")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tbl := []struct {
+		spec string
+		next time.Time
+	}{
+		{"0 3 * * *", time.Date(2024, 6, 30, 3, 0, 0, 0, time.Local)},
+		{"30 0 3 * * *", time.Date(2024, 6, 30, 3, 0, 30, 0, time.Local)},
+		{"CRON_TZ=Europe/Moscow 0 3 * * *", time.Date(2024, 6, 30, 3, 0, 0, 0, msk)},
+		{"CRON_TZ=UTC */15 * * * *", time.Date(2024, 6, 29, 15, 45, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 6, 30, 0, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.spec, func(t *testing.T) {
+			sched, err := makeSchedule(tt.spec)
+			if !assert.NoError(t, err) {
+				return
+			}
+			now := time.Date(2024, 6, 29, 15, 30, 0, 0, tt.next.Location())
+			assert.Equal(t, tt.next.String(), sched.Next(now).String())
+		})
+	}
+
+	_, err = makeSchedule("0 3 * *")
+	assert.ErrorContains(t, err, "invalid schedule")
+}
+
+func TestOpts_http(t *testing.T) {
+	h, err := Opts{Listen: ":8080", ReadyMaxAge: "48h", APIToken: "secret"}.http()
+	assert.NoError(t, err)
+	assert.Equal(t, srv.HTTPOpts{Addr: ":8080", ReadyMaxAge: 48 * time.Hour, APIToken: "secret"}, h)
+
+	_, err = Opts{Listen: ":8080", ReadyMaxAge: "2 days"}.http()
+	assert.ErrorContains(t, err, "invalid ready_max_age")
+}
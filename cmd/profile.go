@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// profile is a named set of settings backed up by its own server.
+type profile struct {
+	name string
+	opts Opts
+}
+
+var profileNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// profiles returns settings of every profile, or a single unnamed one if profiles are not set.
+// Settings of a profile are the global ones overridden by its section of config file and env variables
+// prefixed with the profile name in upper case, e.g. HOME_ZEN_TOKEN or SMALL_BIZ_S3_PREFIX for profiles
+// home and small-biz. Errors of all profiles are returned together with the valid profiles.
+func (opts Opts) profiles() ([]profile, error) {
+	if len(opts.Profiles) == 0 {
+		return []profile{{opts: opts}}, nil
+	}
+
+	res := make([]profile, 0, len(opts.Profiles))
+	seen := map[string]bool{}
+	var errs []error
+	for _, name := range opts.Profiles {
+		if !profileNameRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid profile name %q, only lowercase letters, digits and dashes are allowed", name))
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("duplicate profile %q", name))
+			continue
+		}
+		seen[name] = true
+
+		po, err := opts.forProfile(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("profile %s: %w", name, err))
+			continue
+		}
+		res = append(res, profile{name: name, opts: po})
+	}
+	return res, errors.Join(errs...)
+}
+
+// forProfile returns copy of opts overridden by settings of the profile from config file,
+// and then by env variables prefixed with the profile name.
+func (opts Opts) forProfile(name string) (Opts, error) {
+	res := opts
+	prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	for _, o := range options(&res) {
+		source := configKey(o)
+		values, ok := opts.profileConfig[name][source]
+		if value, inEnv := os.LookupEnv(prefix + o.EnvDefaultKey); inEnv {
+			source, values, ok = prefix+o.EnvDefaultKey, []string{value}, true
+			if o.EnvDefaultDelim != "" {
+				values = strings.Split(value, o.EnvDefaultDelim)
+			}
+		} else if path, inEnv := os.LookupEnv(prefix + o.EnvDefaultKey + "_FILE"); inEnv && isSecret(o) {
+			source, values, ok = prefix+o.EnvDefaultKey+"_FILE", []string{"file:" + path}, true
+		}
+		if !ok {
+			continue
+		}
+
+		var err error
+		if isSecret(o) && usesSecret(opts.command, o) {
+			values, err = resolveSecretValues(values)
+		}
+		if err == nil {
+			err = setOption(&res, o, values)
+		}
+		if err != nil {
+			return Opts{}, fmt.Errorf("invalid %s: %w", source, err)
+		}
+	}
+	return res, nil
+}
+
+// withProfile adds profile name to err, if both are set.
+func withProfile(profile string, err error) error {
+	if profile == "" || err == nil {
+		return err
+	}
+	return fmt.Errorf("%s: %w", profile, err)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpts_profiles(t *testing.T) {
+	opts := Opts{Token: "global", SleepTime: "24h", Timeout: 10, AgeRecipients: []string{"age1global"}}
+
+	res, err := opts.profiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []profile{{opts: opts}}, res, "single unnamed profile")
+
+	t.Setenv("HOME_ZEN_TOKEN", "home_token")
+	t.Setenv("HOME_DEDUP", "true")
+	t.Setenv("SMALL_BIZ_ZEN_TOKEN", "biz_token")
+	t.Setenv("SMALL_BIZ_TIMEOUT", "30")
+	t.Setenv("SMALL_BIZ_AGE_RECIPIENTS", "age1one,age1two")
+	opts.Profiles = []string{"home", "small-biz"}
+	res, err = opts.profiles()
+	assert.NoError(t, err)
+	if !assert.Len(t, res, 2) {
+		return
+	}
+
+	assert.Equal(t, "home", res[0].name)
+	assert.Equal(t, "home_token", res[0].opts.Token)
+	assert.True(t, res[0].opts.Dedup)
+	assert.Equal(t, 10, res[0].opts.Timeout)
+	assert.Equal(t, []string{"age1global"}, res[0].opts.AgeRecipients)
+
+	assert.Equal(t, "small-biz", res[1].name)
+	assert.Equal(t, "biz_token", res[1].opts.Token)
+	assert.False(t, res[1].opts.Dedup)
+	assert.Equal(t, 30, res[1].opts.Timeout)
+	assert.Equal(t, []string{"age1one", "age1two"}, res[1].opts.AgeRecipients, "global values are replaced")
+
+	assert.Equal(t, "global", opts.Token, "global options are not changed")
+	assert.Equal(t, []string{"age1global"}, opts.AgeRecipients, "global options are not changed")
+}
+
+func TestMakeServers(t *testing.T) {
+	t.Setenv("HOME_ZEN_TOKEN", "home_token")
+	servers, err := makeServers(Opts{Token: "global", SleepTime: "24h", Timeout: 10, Profiles: []string{"home", "small-biz"}})
+	assert.NoError(t, err)
+	if assert.Len(t, servers, 2) {
+		assert.Equal(t, "home", servers[0].Profile())
+		assert.Equal(t, "small-biz", servers[1].Profile())
+	}
+}
+
+func TestOpts_profilesErrors(t *testing.T) {
+	_, err := Opts{Profiles: []string{"Home"}}.profiles()
+	assert.ErrorContains(t, err, `invalid profile name "Home"`)
+
+	_, err = Opts{Profiles: []string{"home", "home"}}.profiles()
+	assert.ErrorContains(t, err, `duplicate profile "home"`)
+
+	t.Setenv("HOME_TIMEOUT", "soon")
+	_, err = Opts{Profiles: []string{"home"}}.profiles()
+	assert.ErrorContains(t, err, "profile home: invalid HOME_TIMEOUT")
+
+	t.Setenv("HOME_TIMEOUT", "0")
+	_, err = makeServers(Opts{Profiles: []string{"home"}, SleepTime: "24h", Timeout: 10})
+	assert.EqualError(t, err, "home: timeout must be a positive integer, got 0")
+}
+
+func TestWithProfile(t *testing.T) {
+	err := errors.New("failed")
+	assert.NoError(t, withProfile("home", nil))
+	assert.Equal(t, err, withProfile("", err))
+	assert.EqualError(t, withProfile("home", err), "home: failed")
+	assert.ErrorIs(t, withProfile("home", err), err)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/retention"
+	"github.com/egregors/zenmoney-backup/srv"
+)
+
+// PruneCmd is prune command settings.
+type PruneCmd struct {
+	DryRun bool `long:"dry_run" description:"Only print backups which would be deleted"`
+}
+
+func prune(ctx context.Context, cmd PruneCmd, s *srv.Server, p retention.Policy, out io.Writer) error {
+	if !p.Enabled() {
+		return errors.New("retention policy is not set, use --keep_* options")
+	}
+
+	names, err := s.Prune(ctx, time.Now(), cmd.DryRun)
+	for _, name := range names {
+		if cmd.DryRun {
+			_, _ = fmt.Fprintf(out, "would delete %s\n", name)
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "deleted %s\n", name)
+	}
+	return err
+}
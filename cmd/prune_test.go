@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/retention"
+	"github.com/egregors/zenmoney-backup/srv"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrune(t *testing.T) {
+	st := memStore{
+		"zen_2022-03-12_21-48-00.json": []byte("{}"),
+		"zen_2022-03-13_21-48-00.json": []byte("{}"),
+		"zen_state.json":               []byte("{}"),
+	}
+	p := retention.Policy{KeepLast: 1}
+	s := srv.NewServer("test_token", time.Hour, time.Second, st, nil, srv.WithRetention(p, false))
+
+	out := bytes.Buffer{}
+	err := prune(context.Background(), PruneCmd{DryRun: true}, s, p, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "would delete zen_2022-03-12_21-48-00.json\n", out.String())
+	assert.Len(t, st, 3)
+
+	out.Reset()
+	err = prune(context.Background(), PruneCmd{}, s, p, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "deleted zen_2022-03-12_21-48-00.json\n", out.String())
+	assert.Len(t, st, 2)
+
+	err = prune(context.Background(), PruneCmd{}, s, retention.Policy{}, &out)
+	assert.ErrorContains(t, err, "retention policy is not set")
+}
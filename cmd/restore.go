@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/snapshot"
+	log "github.com/go-pkgz/lgr"
+)
+
+// RestoreSnapshotCmd is restore-snapshot command settings.
+type RestoreSnapshotCmd struct {
+	At  string `long:"at" required:"true" description:"Point in time in local time zone, e.g. 2024-06-29 (end of the day), '2024-06-29 15:30:00' or RFC3339"`
+	Out string `short:"o" long:"out" description:"Output file, stdout if not set"`
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", time.DateOnly}
+
+func restoreSnapshot(ctx context.Context, cmd RestoreSnapshotCmd, src snapshot.Source, profile string) error {
+	at, err := parseTime(cmd.At)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] restoring snapshot at %s", at.Format(time.RFC3339))
+	resp, err := snapshot.Restore(ctx, src, profile, at)
+	if err != nil {
+		return err
+	}
+
+	bs, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Out == "" {
+		_, err = os.Stdout.Write(bs)
+		return err
+	}
+	if err := os.WriteFile(cmd.Out, bs, 0o600); err != nil {
+		return err
+	}
+	log.Printf("[INFO] %s saved", cmd.Out)
+	return nil
+}
+
+// parseTime parses time in one of timeLayouts, in local time zone unless it's set explicitly.
+// Date without time is the end of the day, so backups made during the day are included.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			if layout == time.DateOnly {
+				return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't parse time %q, expected format is 2006-01-02, '2006-01-02 15:04:05' or RFC3339", s)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// memStore is srv.Saver keeping files in memory.
+type memStore map[string][]byte
+
+func (m memStore) Save(_ context.Context, filename string, bs []byte) error {
+	m[filename] = bs
+	return nil
+}
+
+func (m memStore) Load(_ context.Context, filename string) ([]byte, error) {
+	bs, ok := m[filename]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return bs, nil
+}
+
+func (m memStore) List(_ context.Context) ([]string, error) {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func (m memStore) Delete(_ context.Context, filename string) error {
+	delete(m, filename)
+	return nil
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	src := memStore{
+		"zen_2024-06-29_10-00-00.json":       []byte(`{"serverTimestamp":100,"tag":[{"id":"t1","title":"Food","changed":10}]}`),
+		"zen_2024-06-29_11-00-00.delta.json": []byte(`{"serverTimestamp":200,"tag":[{"id":"t2","title":"Fun","changed":150}]}`),
+		"zen_2024-06-30_11-00-00.delta.json": []byte(`{"serverTimestamp":300,"deletion":[{"id":"t1","object":"tag"}]}`),
+	}
+	out := filepath.Join(t.TempDir(), "snapshot.json")
+
+	err := restoreSnapshot(context.Background(), RestoreSnapshotCmd{At: "2024-06-29", Out: out}, src, "")
+	assert.NoError(t, err)
+
+	bs, err := os.ReadFile(out) // #nosec G304 - test file
+	assert.NoError(t, err)
+	var resp models.Response
+	assert.NoError(t, json.Unmarshal(bs, &resp))
+	assert.Equal(t, 200, resp.ServerTimestamp, "backups of the whole day are applied")
+	assert.Len(t, resp.Tag, 2)
+
+	err = restoreSnapshot(context.Background(), RestoreSnapshotCmd{At: "2024-06-28", Out: out}, src, "")
+	assert.ErrorContains(t, err, "no full backup found")
+
+	err = restoreSnapshot(context.Background(), RestoreSnapshotCmd{At: "yesterday", Out: out}, src, "")
+	assert.ErrorContains(t, err, "can't parse time")
+}
+
+func TestParseTime(t *testing.T) {
+	exp := time.Date(2024, 6, 29, 15, 30, 0, 0, time.Local)
+	for _, s := range []string{"2024-06-29 15:30:00", "2024-06-29T15:30:00"} {
+		tm, err := parseTime(s)
+		assert.NoError(t, err)
+		assert.True(t, exp.Equal(tm), s)
+	}
+
+	tm, err := parseTime("2024-06-29T15:30:00Z")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC).Equal(tm))
+
+	tm, err = parseTime("2024-06-29")
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 6, 29, 23, 59, 59, 999999999, time.Local).Equal(tm), "end of the day")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/secret"
+	"github.com/jessevdk/go-flags"
+)
+
+// secretTimeout limits time of resolving a secret, e.g. by a password manager command.
+const secretTimeout = 30 * time.Second
+
+var secrets = secret.Default()
+
+// storeSecrets are secrets needed to read backups: storage credentials and age passphrase.
+var storeSecrets = []string{"AGE_PASSPHRASE", "S3_ACCESS_KEY", "S3_SECRET_KEY"}
+
+// commandSecrets are env keys of secret options used by commands, by command name, only they are
+// resolved. Commands not listed, e.g. the daemon itself and config validate, use all secrets.
+var commandSecrets = map[string][]string{
+	"backup":           append([]string{"ZEN_TOKEN", "NOTIFY_URL", "TELEGRAM_TOKEN", "SMTP_PASSWORD", "WEBHOOK_URL"}, storeSecrets...),
+	"prune":            append([]string{"NOTIFY_URL", "TELEGRAM_TOKEN", "SMTP_PASSWORD", "WEBHOOK_URL"}, storeSecrets...),
+	"restore-snapshot": storeSecrets,
+	"export":           storeSecrets,
+	"decrypt":          {"AGE_PASSPHRASE"},
+	"healthcheck":      {},
+	"config print":     {}, // secrets are redacted
+}
+
+// usesSecret returns true if command uses secret option o, see commandSecrets.
+func usesSecret(command string, o *flags.Option) bool {
+	if strings.HasPrefix(command, "export ") {
+		command = "export"
+	}
+	keys, ok := commandSecrets[command]
+	return !ok || slices.Contains(keys, o.EnvDefaultKey)
+}
+
+// isSecret returns true if option is tagged as secret, such options are resolved by secrets
+// and redacted by config print.
+func isSecret(o *flags.Option) bool {
+	return o.Field().Tag.Get("secret") == "true"
+}
+
+// applySecretFiles sets secret options from *_FILE env variables, e.g. ZEN_TOKEN_FILE, as used
+// by Docker and Kubernetes secrets. They have the same precedence as the env variables themselves.
+func applySecretFiles(p *flags.Parser, opts *Opts) error {
+	var errs []error
+	for _, o := range optionsOf(p) {
+		path, ok := os.LookupEnv(o.EnvDefaultKey + "_FILE")
+		if !ok || !isSecret(o) || (o.IsSet() && !o.IsSetDefault()) {
+			continue
+		}
+		if _, ok := os.LookupEnv(o.EnvDefaultKey); ok {
+			errs = append(errs, fmt.Errorf("both %s and %s_FILE are set", o.EnvDefaultKey, o.EnvDefaultKey))
+			continue
+		}
+		if err := setOption(opts, o, []string{"file:" + path}); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s_FILE: %w", o.EnvDefaultKey, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolveSecrets replaces references in secret options used by the active command by the secrets,
+// see secret.Default.
+func (opts *Opts) resolveSecrets() error {
+	var errs []error
+	for _, o := range options(opts) {
+		if !isSecret(o) || !usesSecret(opts.command, o) {
+			continue
+		}
+		values, ok := o.Value().([]string)
+		if !ok {
+			values = []string{fmt.Sprint(o.Value())}
+		}
+		values, err := resolveSecretValues(values)
+		if err == nil {
+			err = setOption(opts, o, values)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", configKey(o), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolveSecretValues resolves every value of a list option, e.g. NOTIFY_URL.
+func resolveSecretValues(values []string) ([]string, error) {
+	res := make([]string, len(values))
+	for i, v := range values {
+		var err error
+		if res[i], err = resolveSecret(v); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func resolveSecret(value string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+	return secrets.Resolve(ctx, value)
+}
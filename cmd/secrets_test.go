@@ -0,0 +1,114 @@
+package main
+
+import (
+	"cmp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSettings_secrets(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile, keyFile := filepath.Join(dir, "zen_token"), filepath.Join(dir, "s3_secret_key")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("file_token\n"), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, []byte("file_key"), 0o600))
+	t.Setenv("ZEN_TOKEN_FILE", tokenFile)
+	t.Setenv("ZENB_TEST_PASSPHRASE", "env_passphrase")
+	t.Setenv("S3_SECRET_KEY", "file:"+keyFile)
+	t.Setenv("HOME_ZEN_TOKEN_FILE", tokenFile)
+	t.Setenv("WORK_ZEN_TOKEN", "exec:echo work_token")
+	t.Setenv("ZENB_TEST_TGRAM", "tgram://123:abc/42")
+	t.Setenv("NOTIFY_URL", "ntfy://topic env:ZENB_TEST_TGRAM")
+
+	var opts Opts
+	p := flags.NewParser(&opts, flags.None)
+	p.SubcommandsOptional = true
+	_, err := p.ParseArgs([]string{"--age_passphrase", "env:ZENB_TEST_PASSPHRASE", "--s3_bucket", "file:not_a_secret",
+		"--profile", "home", "--profile", "work"})
+	assert.NoError(t, err)
+	assert.NoError(t, loadSettings(p, &opts))
+
+	assert.Equal(t, "file_token", opts.Token)
+	assert.Equal(t, "env_passphrase", opts.AgePassphrase)
+	assert.Equal(t, "file_key", opts.S3SecretKey)
+	assert.Equal(t, "file:not_a_secret", opts.S3Bucket, "only secret options are resolved")
+	assert.Equal(t, []string{"ntfy://topic", "tgram://123:abc/42"}, opts.NotifyURL, "every value of a list is resolved")
+
+	profiles, err := opts.profiles()
+	assert.NoError(t, err)
+	if assert.Len(t, profiles, 2) {
+		assert.Equal(t, "file_token", profiles[0].opts.Token)
+		assert.Equal(t, "work_token", profiles[1].opts.Token)
+		assert.Equal(t, "env_passphrase", profiles[1].opts.AgePassphrase, "global secrets are not resolved again")
+	}
+}
+
+func TestLoadSettings_secretsErrors(t *testing.T) {
+	t.Setenv("ZEN_TOKEN", "token")
+	t.Setenv("ZEN_TOKEN_FILE", "/run/secrets/zen_token")
+	t.Setenv("API_TOKEN", "env:ZENB_TEST_MISSING")
+	t.Setenv("HOME_ZEN_TOKEN", "file:/zenb/missing")
+
+	var opts Opts
+	p := flags.NewParser(&opts, flags.None)
+	p.SubcommandsOptional = true
+	_, err := p.ParseArgs([]string{"--profile", "home"})
+	assert.NoError(t, err)
+
+	err = loadSettings(p, &opts)
+	assert.ErrorContains(t, err, "both ZEN_TOKEN and ZEN_TOKEN_FILE are set")
+	assert.ErrorContains(t, err, "invalid api_token: can't resolve env secret: ZENB_TEST_MISSING is not set")
+
+	_, err = opts.profiles()
+	assert.EqualError(t, err, "profile home: invalid HOME_ZEN_TOKEN: can't resolve file secret: open /zenb/missing: no such file or directory")
+}
+
+func TestLoadSettings_secretsOfCommand(t *testing.T) {
+	t.Setenv("ZEN_TOKEN", "env:ZENB_TEST_MISSING")
+	t.Setenv("AGE_PASSPHRASE", "env:ZENB_TEST_PASSPHRASE")
+	t.Setenv("ZENB_TEST_PASSPHRASE", "env_passphrase")
+	t.Setenv("HOME_ZEN_TOKEN", "file:/zenb/missing")
+
+	tbl := []struct {
+		args       []string
+		token      string
+		passphrase string
+		err        string
+	}{
+		{args: []string{"healthcheck"}, token: "env:ZENB_TEST_MISSING", passphrase: "env:ZENB_TEST_PASSPHRASE"},
+		{args: []string{"config", "print"}, token: "env:ZENB_TEST_MISSING", passphrase: "env:ZENB_TEST_PASSPHRASE"},
+		{args: []string{"decrypt", "zen.json.age"}, token: "env:ZENB_TEST_MISSING", passphrase: "env_passphrase"},
+		{args: []string{"export", "csv"}, token: "env:ZENB_TEST_MISSING", passphrase: "env_passphrase"},
+		{args: []string{"backup"}, err: "invalid zen_token: can't resolve env secret: ZENB_TEST_MISSING is not set"},
+		{args: []string{"config", "validate"}, err: "invalid zen_token: can't resolve env secret: ZENB_TEST_MISSING is not set"},
+		{args: nil, err: "invalid zen_token: can't resolve env secret: ZENB_TEST_MISSING is not set"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(cmp.Or(strings.Join(tt.args, " "), "daemon"), func(t *testing.T) {
+			var opts Opts
+			p := flags.NewParser(&opts, flags.None)
+			p.SubcommandsOptional = true
+			_, err := p.ParseArgs(append([]string{"--profile", "home"}, tt.args...))
+			assert.NoError(t, err)
+
+			err = loadSettings(p, &opts)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.token, opts.Token)
+			assert.Equal(t, tt.passphrase, opts.AgePassphrase)
+			profiles, err := opts.profiles()
+			assert.NoError(t, err, "unused secrets of profiles are not resolved")
+			if assert.Len(t, profiles, 1) {
+				assert.Equal(t, "file:/zenb/missing", profiles[0].opts.Token)
+			}
+		})
+	}
+}
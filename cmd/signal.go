@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/egregors/zenmoney-backup/srv"
+	log "github.com/go-pkgz/lgr"
+)
+
+// triggerOnSignal starts backups of all servers on SIGUSR1.
+func triggerOnSignal(servers []*srv.Server) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	for range usr1 {
+		for _, s := range servers {
+			if !s.Trigger() {
+				log.Printf("[INFO] backup is already pending, SIGUSR1 ignored")
+			}
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+import "github.com/egregors/zenmoney-backup/srv"
+
+// triggerOnSignal does nothing, there is no SIGUSR1 on Windows, backups are triggered by POST /backup only.
+func triggerOnSignal(_ []*srv.Server) {}
@@ -0,0 +1,98 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+const ageExt = ".age"
+
+// Age is encryption stage using age (https://age-encryption.org), either to X25519
+// public keys or with a scrypt passphrase.
+type Age struct {
+	recipients []age.Recipient
+	identities []age.Identity
+}
+
+// NewAge makes Age encrypting backups to recipients (age1... public keys) or with passphrase.
+// age doesn't allow to combine passphrase with public keys.
+func NewAge(recipients []string, passphrase string) (*Age, error) {
+	if passphrase != "" && len(recipients) > 0 {
+		return nil, errors.New("age passphrase can't be combined with recipients")
+	}
+
+	res := &Age{}
+	if passphrase != "" {
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		res.recipients = append(res.recipients, r)
+		return res, nil
+	}
+
+	for _, s := range recipients {
+		r, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", s, err)
+		}
+		res.recipients = append(res.recipients, r)
+	}
+	if len(res.recipients) == 0 {
+		return nil, errors.New("age recipient or passphrase is required")
+	}
+	return res, nil
+}
+
+// NewAgeDecrypter makes Age able only to decrypt backups, with identities from
+// identityFile (AGE-SECRET-KEY-1...) and/or passphrase.
+func NewAgeDecrypter(identityFile, passphrase string) (*Age, error) {
+	res := &Age{}
+	if identityFile != "" {
+		f, err := os.Open(identityFile) // #nosec G304 - file is set by user
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		ids, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age identity file %s: %w", identityFile, err)
+		}
+		res.identities = append(res.identities, ids...)
+	}
+	if passphrase != "" {
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		res.identities = append(res.identities, id)
+	}
+	if len(res.identities) == 0 {
+		return nil, errors.New("age identity file or passphrase is required")
+	}
+	return res, nil
+}
+
+// Ext returns extension of encrypted files.
+func (a *Age) Ext() string {
+	return ageExt
+}
+
+// Wrap returns writer encrypting everything written to w.
+func (a *Age) Wrap(w io.Writer) (io.WriteCloser, error) {
+	if len(a.recipients) == 0 {
+		return nil, errors.New("no age recipients to encrypt to")
+	}
+	return age.Encrypt(w, a.recipients...)
+}
+
+func (a *Age) decrypt(r io.Reader) (io.Reader, error) {
+	return age.Decrypt(r, a.identities...)
+}
@@ -0,0 +1,91 @@
+package codec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAge(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	_, err = NewAge(nil, "")
+	assert.EqualError(t, err, "age recipient or passphrase is required")
+
+	_, err = NewAge([]string{id.Recipient().String()}, "secret")
+	assert.EqualError(t, err, "age passphrase can't be combined with recipients")
+
+	_, err = NewAge([]string{"age1nope"}, "")
+	assert.ErrorContains(t, err, `invalid age recipient "age1nope"`)
+
+	a, err := NewAge([]string{id.Recipient().String()}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, ".age", a.Ext())
+}
+
+func TestAge_Recipients(t *testing.T) {
+	id1, _ := age.GenerateX25519Identity()
+	id2, _ := age.GenerateX25519Identity()
+	a, err := NewAge([]string{id1.Recipient().String(), id2.Recipient().String()}, "")
+	assert.NoError(t, err)
+
+	bs, err := Encode(map[string]string{"account": "secret"}, a)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(bs), "secret")
+
+	// every recipient can decrypt
+	for _, id := range []*age.X25519Identity{id1, id2} {
+		idFile := filepath.Join(t.TempDir(), "key.txt")
+		assert.NoError(t, os.WriteFile(idFile, []byte(id.String()+"\n"), 0o600))
+
+		dec, err := NewAgeDecrypter(idFile, "")
+		assert.NoError(t, err)
+		plain, err := NewDecoder(dec).Decode("zen_1.json.age", bs)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"account":"secret"}`, string(plain))
+	}
+
+	// wrong identity
+	other, _ := age.GenerateX25519Identity()
+	idFile := filepath.Join(t.TempDir(), "key.txt")
+	assert.NoError(t, os.WriteFile(idFile, []byte(other.String()+"\n"), 0o600))
+	dec, err := NewAgeDecrypter(idFile, "")
+	assert.NoError(t, err)
+	_, err = NewDecoder(dec).Decode("zen_1.json.age", bs)
+	assert.ErrorContains(t, err, "can't decode zen_1.json.age")
+}
+
+func TestAge_Passphrase(t *testing.T) {
+	a, err := NewAge(nil, "correct horse battery staple")
+	assert.NoError(t, err)
+	bs, err := Encode([]int{1, 2, 3}, a)
+	assert.NoError(t, err)
+
+	dec, err := NewAgeDecrypter("", "correct horse battery staple")
+	assert.NoError(t, err)
+	plain, err := NewDecoder(dec).Decode("zen_1.json.age", bs)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[1,2,3]`, string(plain))
+
+	dec, err = NewAgeDecrypter("", "wrong")
+	assert.NoError(t, err)
+	_, err = NewDecoder(dec).Decode("zen_1.json.age", bs)
+	assert.Error(t, err)
+}
+
+func TestNewAgeDecrypter(t *testing.T) {
+	_, err := NewAgeDecrypter("", "")
+	assert.EqualError(t, err, "age identity file or passphrase is required")
+
+	_, err = NewAgeDecrypter(filepath.Join(t.TempDir(), "nope.txt"), "")
+	assert.Error(t, err)
+
+	idFile := filepath.Join(t.TempDir(), "key.txt")
+	assert.NoError(t, os.WriteFile(idFile, []byte("not a key\n"), 0o600))
+	_, err = NewAgeDecrypter(idFile, "")
+	assert.ErrorContains(t, err, "invalid age identity file")
+}
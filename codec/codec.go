@@ -0,0 +1,207 @@
+// Package codec provides pipeline stages applied to backups before they are stored
+// (layout, compression, encryption) and decoding of stored backups back to plain JSON.
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Encoder is a pipeline stage. It wraps the writer backup is streamed to and adds
+// its extension to the backup file name, e.g. ".age".
+type Encoder interface {
+	Ext() string
+	Wrap(w io.Writer) (io.WriteCloser, error)
+}
+
+// formats are extensions of plain content encoders are applied to: backups and their exports.
+var formats = map[string]bool{".json": true, ".csv": true, ".beancount": true, ".ledger": true, ".ofx": true, ".qif": true}
+
+// Encode streams v marshaled to JSON through encoders, the first encoder is applied first.
+func Encode(v any, encoders ...Encoder) ([]byte, error) {
+	return EncodeFunc(func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(v)
+	}, encoders...)
+}
+
+// EncodeFunc streams content written by write through encoders, e.g. exports of backups.
+func EncodeFunc(write func(w io.Writer) error, encoders ...Encoder) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := EncodeTo(&buf, write, encoders...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeTo streams content written by write through encoders to w, stages pass data to each other
+// as it's written.
+func EncodeTo(w io.Writer, write func(w io.Writer) error, encoders ...Encoder) error {
+	closers := make([]io.Closer, 0, len(encoders))
+	for i := len(encoders) - 1; i >= 0; i-- {
+		wc, err := encoders[i].Wrap(w)
+		if err != nil {
+			closeAll(closers)
+			return err
+		}
+		closers = append(closers, wc)
+		w = wc
+	}
+
+	if err := write(w); err != nil {
+		closeAll(closers)
+		return err
+	}
+	// close from the outermost stage, so every stage flushes to the next one
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			closeAll(closers[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// closeAll releases stages after a failure, from the outermost one, errors are ignored.
+func closeAll(closers []io.Closer) {
+	for i := len(closers) - 1; i >= 0; i-- {
+		_ = closers[i].Close()
+	}
+}
+
+// Ext returns file name extension added by encoders.
+func Ext(encoders ...Encoder) string {
+	res := ""
+	for _, e := range encoders {
+		res += e.Ext()
+	}
+	return res
+}
+
+// Decoder reverts encoders found by file name extensions.
+type Decoder struct {
+	decoders map[string]func(r io.Reader) (io.Reader, error)
+}
+
+// NewDecoder makes Decoder. Encrypted files can be decoded only if age is set.
+func NewDecoder(age *Age) *Decoder {
+	d := &Decoder{decoders: map[string]func(r io.Reader) (io.Reader, error){
+		gzipExt: gunzip,
+		zstdExt: unzstd,
+		tarExt:  untar,
+	}}
+	if age != nil {
+		d.decoders[ageExt] = age.decrypt
+	}
+	return d
+}
+
+// Decode reverts all encoders applied to the file and returns plain content.
+func (d *Decoder) Decode(name string, bs []byte) (res []byte, err error) {
+	r, err := d.NewReader(name, bytes.NewReader(bs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := r.Close(); err == nil && cerr != nil {
+			res, err = nil, fmt.Errorf("can't decode %s: %w", name, cerr)
+		}
+	}()
+	return io.ReadAll(r)
+}
+
+// NewReader returns reader of plain content of the file read from r, all encoders applied to the file
+// are reverted as it's read. Close releases every stage, e.g. zstd decoder goroutines.
+func (d *Decoder) NewReader(name string, r io.Reader) (io.ReadCloser, error) {
+	res := &stagesReader{Reader: r}
+	for file := name; ; {
+		ext := extension(file)
+		if formats[ext] || ext == "" {
+			return res, nil
+		}
+		dec, ok := d.decoders[ext]
+		if !ok {
+			_ = res.Close()
+			if ext == ageExt {
+				return nil, fmt.Errorf("%s is encrypted, age identity or passphrase is required", name)
+			}
+			return nil, fmt.Errorf("can't decode %s: unknown extension %s", name, ext)
+		}
+		next, err := dec(res.Reader)
+		if err != nil {
+			_ = res.Close()
+			return nil, fmt.Errorf("can't decode %s: %w", name, err)
+		}
+		if c, ok := next.(io.Closer); ok {
+			res.closers = append(res.closers, c)
+		}
+		res.Reader = next
+		file = strings.TrimSuffix(file, ext)
+	}
+}
+
+// stagesReader reads the last decoding stage, closers of all stages are closed with it.
+type stagesReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes stages from the last one, the one reading from the others.
+func (s *stagesReader) Close() error {
+	errs := make([]error, 0, len(s.closers))
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		errs = append(errs, s.closers[i].Close())
+	}
+	s.closers = nil
+	return errors.Join(errs...)
+}
+
+// TrimExt returns file name without extensions added by encoders.
+func TrimExt(name string) string {
+	for {
+		ext := extension(name)
+		if formats[ext] || ext == "" {
+			return name
+		}
+		name = strings.TrimSuffix(name, ext)
+	}
+}
+
+// Source returns loader decoding loaded files.
+func (d *Decoder) Source(src Loader) Loader {
+	return decodingLoader{src: src, dec: d}
+}
+
+// Loader is a storage backups are read from.
+type Loader interface {
+	List(ctx context.Context) ([]string, error)
+	Load(ctx context.Context, filename string) ([]byte, error)
+}
+
+type decodingLoader struct {
+	src Loader
+	dec *Decoder
+}
+
+func (l decodingLoader) List(ctx context.Context) ([]string, error) {
+	return l.src.List(ctx)
+}
+
+func (l decodingLoader) Load(ctx context.Context, filename string) ([]byte, error) {
+	bs, err := l.src.Load(ctx, filename)
+	if err != nil {
+		return nil, err
+	}
+	return l.dec.Decode(filename, bs)
+}
+
+func extension(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i:]
+	}
+	return ""
+}
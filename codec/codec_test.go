@@ -0,0 +1,180 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// upperEncoder is a test stage turning all written letters to upper case.
+type upperEncoder struct{}
+
+func (upperEncoder) Ext() string { return ".up" }
+
+func (upperEncoder) Wrap(w io.Writer) (io.WriteCloser, error) {
+	return upperWriter{w: w}, nil
+}
+
+type upperWriter struct{ w io.Writer }
+
+func (u upperWriter) Write(p []byte) (int, error) { return u.w.Write(bytes.ToUpper(p)) }
+func (u upperWriter) Close() error                { return nil }
+
+type memLoader map[string][]byte
+
+func (m memLoader) List(_ context.Context) ([]string, error) {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res, nil
+}
+
+func (m memLoader) Load(_ context.Context, filename string) ([]byte, error) { return m[filename], nil }
+
+func TestEncode(t *testing.T) {
+	v := map[string]string{"a": "b"}
+
+	bs, err := Encode(v)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a":"b"}`, string(bs))
+
+	bs, err = Encode(v, upperEncoder{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"A":"B"}`, string(bs))
+}
+
+func TestEncodeFunc(t *testing.T) {
+	bs, err := EncodeFunc(func(w io.Writer) error {
+		_, err := io.WriteString(w, "date,amount\n")
+		return err
+	}, upperEncoder{})
+	assert.NoError(t, err)
+	assert.Equal(t, "DATE,AMOUNT\n", string(bs))
+
+	_, err = EncodeFunc(func(io.Writer) error { return errors.New("failed") })
+	assert.EqualError(t, err, "failed")
+}
+
+func TestExt(t *testing.T) {
+	assert.Empty(t, Ext())
+	assert.Equal(t, ".up.age", Ext(upperEncoder{}, &Age{}))
+}
+
+func TestTrimExt(t *testing.T) {
+	assert.Equal(t, "zen_1.json", TrimExt("zen_1.json"))
+	assert.Equal(t, "zen_1.delta.json", TrimExt("zen_1.delta.json.age"))
+	assert.Equal(t, "zen_1.json", TrimExt("zen_1.json.up.age"))
+	assert.Equal(t, "zen_1.csv", TrimExt("zen_1.csv.gz"))
+	assert.Equal(t, "zen_1.beancount", TrimExt("zen_1.beancount.zst.age"))
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	d := NewDecoder(nil)
+
+	bs, err := d.Decode("zen_1.json", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(bs))
+
+	_, err = d.Decode("zen_1.json.age", []byte(`{}`))
+	assert.EqualError(t, err, "zen_1.json.age is encrypted, age identity or passphrase is required")
+
+	bs, err = d.Decode("zen_1.csv", []byte("date,amount\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "date,amount\n", string(bs))
+
+	_, err = d.Decode("zen_1.json.rar", []byte(`{}`))
+	assert.EqualError(t, err, "can't decode zen_1.json.rar: unknown extension .rar")
+}
+
+// closeRecorder is a decoding stage recording its Close.
+type closeRecorder struct {
+	io.Reader
+	name   string
+	closed *[]string
+}
+
+func (c closeRecorder) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+func TestDecoder_NewReader(t *testing.T) {
+	var closed []string
+	stage := func(name string) func(io.Reader) (io.Reader, error) {
+		return func(r io.Reader) (io.Reader, error) {
+			return closeRecorder{Reader: r, name: name, closed: &closed}, nil
+		}
+	}
+	d := NewDecoder(nil)
+	d.decoders[".a"] = stage("a")
+	d.decoders[".b"] = stage("b")
+	d.decoders[".err"] = func(io.Reader) (io.Reader, error) { return nil, errors.New("failed") }
+
+	r, err := d.NewReader("zen_1.json.b.a", strings.NewReader(`{}`))
+	assert.NoError(t, err)
+	bs, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(bs))
+	assert.Empty(t, closed)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, []string{"b", "a"}, closed, "every stage is closed, the last one first")
+
+	closed = nil
+	_, err = d.Decode("zen_1.json.a.b", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, closed)
+
+	closed = nil
+	_, err = d.NewReader("zen_1.json.err.a", strings.NewReader(`{}`))
+	assert.EqualError(t, err, "can't decode zen_1.json.err.a: failed")
+	assert.Equal(t, []string{"a"}, closed, "opened stages are closed on failure")
+
+	// compressed tar has zstd decoder in the middle of the pipeline
+	c, err := NewCompressor("zstd", 0)
+	assert.NoError(t, err)
+	bs, err = Encode(map[string]any{"serverTimestamp": 1}, NewSplit(""), c)
+	assert.NoError(t, err)
+	r, err = NewDecoder(nil).NewReader("zen_1.json.tar.zst", bytes.NewReader(bs))
+	assert.NoError(t, err)
+	bs, err = io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"serverTimestamp":1}`, string(bs))
+	assert.NoError(t, r.Close())
+}
+
+func TestEncodeTo(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, EncodeTo(&buf, func(w io.Writer) error {
+		_, err := io.WriteString(w, "date,amount\n")
+		return err
+	}, upperEncoder{}))
+	assert.Equal(t, "DATE,AMOUNT\n", buf.String())
+}
+
+func TestDecoder_Source(t *testing.T) {
+	a, err := NewAge(nil, "secret")
+	assert.NoError(t, err)
+	bs, err := Encode(map[string]int{"serverTimestamp": 1}, a)
+	assert.NoError(t, err)
+
+	dec, err := NewAgeDecrypter("", "secret")
+	assert.NoError(t, err)
+	src := NewDecoder(dec).Source(memLoader{"zen_1.json.age": bs})
+
+	names, err := src.List(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zen_1.json.age"}, names)
+
+	bs, err = src.Load(context.Background(), "zen_1.json.age")
+	assert.NoError(t, err)
+	var res map[string]int
+	assert.NoError(t, json.Unmarshal(bs, &res))
+	assert.Equal(t, 1, res["serverTimestamp"])
+}
@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	gzipExt = ".gz"
+	zstdExt = ".zst"
+)
+
+// NewCompressor makes compression stage by algorithm name, gzip or zstd.
+// Zero level means the default level of the algorithm.
+func NewCompressor(algo string, level int) (Encoder, error) {
+	switch algo {
+	case "gzip":
+		if level != 0 && (level < gzip.BestSpeed || level > gzip.BestCompression) {
+			return nil, fmt.Errorf("gzip level must be in [%d, %d] or 0 for default, got %d", gzip.BestSpeed, gzip.BestCompression, level)
+		}
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return Gzip{level: level}, nil
+	case "zstd":
+		if level < 0 || level > 22 {
+			return nil, fmt.Errorf("zstd level must be in [1, 22] or 0 for default, got %d", level)
+		}
+		return Zstd{level: level}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression %q", algo)
+	}
+}
+
+// Gzip is gzip compression stage.
+type Gzip struct {
+	level int
+}
+
+// Ext returns extension of gzip compressed files.
+func (g Gzip) Ext() string {
+	return gzipExt
+}
+
+// Wrap returns writer compressing everything written to w.
+func (g Gzip) Wrap(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, g.level)
+}
+
+// Zstd is zstd compression stage.
+type Zstd struct {
+	level int
+}
+
+// Ext returns extension of zstd compressed files.
+func (z Zstd) Ext() string {
+	return zstdExt
+}
+
+// Wrap returns writer compressing everything written to w.
+func (z Zstd) Wrap(w io.Writer) (io.WriteCloser, error) {
+	level := zstd.SpeedDefault
+	if z.level != 0 {
+		level = zstd.EncoderLevelFromZstd(z.level)
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+}
+
+func gunzip(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+func unzstd(r io.Reader) (io.Reader, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCompressor(t *testing.T) {
+	c, err := NewCompressor("gzip", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ".gz", c.Ext())
+
+	c, err = NewCompressor("zstd", 19)
+	assert.NoError(t, err)
+	assert.Equal(t, ".zst", c.Ext())
+
+	_, err = NewCompressor("gzip", 10)
+	assert.EqualError(t, err, "gzip level must be in [1, 9] or 0 for default, got 10")
+
+	_, err = NewCompressor("zstd", 0)
+	assert.NoError(t, err)
+
+	_, err = NewCompressor("zstd", 23)
+	assert.EqualError(t, err, "zstd level must be in [1, 22] or 0 for default, got 23")
+
+	_, err = NewCompressor("zstd", -1)
+	assert.EqualError(t, err, "zstd level must be in [1, 22] or 0 for default, got -1")
+
+	_, err = NewCompressor("rar", 0)
+	assert.EqualError(t, err, `unknown compression "rar"`)
+}
+
+func TestCompression(t *testing.T) {
+	v := map[string]string{"payee": strings.Repeat("Coffee Shop ", 1000)}
+	plain, err := Encode(v)
+	assert.NoError(t, err)
+
+	for _, algo := range []string{"gzip", "zstd"} {
+		for _, level := range []int{0, 1, 9} {
+			c, err := NewCompressor(algo, level)
+			assert.NoError(t, err)
+
+			bs, err := Encode(v, c)
+			assert.NoError(t, err)
+			assert.Less(t, len(bs)*10, len(plain), "%s/%d", algo, level)
+
+			res, err := NewDecoder(nil).Decode("zen_1.json"+c.Ext(), bs)
+			assert.NoError(t, err)
+			assert.Equal(t, string(plain), string(res))
+		}
+	}
+}
+
+func TestCompressionWithEncryption(t *testing.T) {
+	c, err := NewCompressor("zstd", 0)
+	assert.NoError(t, err)
+	a, err := NewAge(nil, "secret")
+	assert.NoError(t, err)
+
+	bs, err := Encode(map[string]int{"serverTimestamp": 1}, c, a)
+	assert.NoError(t, err)
+	assert.Equal(t, ".zst.age", Ext(c, a))
+
+	dec, err := NewAgeDecrypter("", "secret")
+	assert.NoError(t, err)
+	res, err := NewDecoder(dec).Decode("zen_1.json.zst.age", bs)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"serverTimestamp":1}`, string(res))
+	assert.Equal(t, "zen_1.json", TrimExt("zen_1.json.zst.age"))
+}
@@ -0,0 +1,238 @@
+package codec
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	tarExt         = ".tar"
+	manifestName   = "manifest.json"
+	manifestFormat = 1
+)
+
+// jsonLines are entities saved as JSON Lines, an entity per line, the others are saved as indented JSON.
+var jsonLines = map[string]bool{"transaction": true, "reminderMarker": true}
+
+// Manifest describes files of split backup, see Split.
+type Manifest struct {
+	Format          int            `json:"format"`
+	Version         string         `json:"version"` // of the app made the backup
+	ServerTimestamp int            `json:"serverTimestamp"`
+	Files           []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file of split backup with entities of a type. Name is empty if entities are null,
+// there is no file for them, so null and empty lists are told apart.
+type ManifestFile struct {
+	Name   string `json:"name"`   // e.g. transaction.jsonl
+	Entity string `json:"entity"` // e.g. transaction
+	Count  int    `json:"count"`
+	SHA256 string `json:"sha256"`
+}
+
+// File is a file of split backup.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Split is a layout stage saving backup as tar archive with a file per entity type and manifest.json,
+// e.g. account.json and transaction.jsonl. Large collections are saved as JSON Lines, the others as
+// indented JSON, so backups are easy to diff, grep and load partially.
+type Split struct {
+	version string
+}
+
+// NewSplit makes split layout stage, version of the app is saved to manifests.
+func NewSplit(version string) Split {
+	return Split{version: version}
+}
+
+// Ext returns extension of split backups.
+func (s Split) Ext() string {
+	return tarExt
+}
+
+// Wrap returns writer saving JSON backup written to it as tar archive to w.
+func (s Split) Wrap(w io.Writer) (io.WriteCloser, error) {
+	return &splitWriter{w: w, version: s.version}, nil
+}
+
+type splitWriter struct {
+	buf     bytes.Buffer
+	w       io.Writer
+	version string
+}
+
+func (s *splitWriter) Write(p []byte) (int, error) {
+	return s.buf.Write(p)
+}
+
+// Close splits the backup and writes the archive.
+func (s *splitWriter) Close() error {
+	m, files, err := split(s.buf.Bytes(), s.version)
+	if err != nil {
+		return err
+	}
+	modTime := time.Unix(int64(m.ServerTimestamp), 0)
+
+	tw := tar.NewWriter(s.w)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.Name, Mode: 0o600, Size: int64(len(f.Data)), ModTime: modTime}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// SplitFiles splits JSON backup to a file per entity type, manifest.json is the first one.
+func SplitFiles(backup []byte, version string) ([]File, error) {
+	_, files, err := split(backup, version)
+	return files, err
+}
+
+func split(backup []byte, version string) (Manifest, []File, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(backup, &fields); err != nil {
+		return Manifest{}, nil, fmt.Errorf("can't split backup: %w", err)
+	}
+
+	m := Manifest{Format: manifestFormat, Version: version}
+	if ts, ok := fields["serverTimestamp"]; ok {
+		if err := json.Unmarshal(ts, &m.ServerTimestamp); err != nil {
+			return Manifest{}, nil, fmt.Errorf("can't split backup: %w", err)
+		}
+		delete(fields, "serverTimestamp")
+	}
+
+	files := []File{{Name: manifestName}}
+	for _, entity := range slices.Sorted(maps.Keys(fields)) {
+		if bytes.Equal(bytes.TrimSpace(fields[entity]), []byte("null")) {
+			m.Files = append(m.Files, ManifestFile{Entity: entity})
+			continue
+		}
+		var items []json.RawMessage
+		isList := json.Unmarshal(fields[entity], &items) == nil
+
+		f := File{Name: entity + ".json"}
+		if isList && jsonLines[entity] {
+			f.Name = entity + ".jsonl"
+			buf := bytes.Buffer{}
+			for _, item := range items {
+				if err := json.Compact(&buf, item); err != nil {
+					return Manifest{}, nil, err
+				}
+				buf.WriteByte('\n')
+			}
+			f.Data = buf.Bytes()
+		} else {
+			buf := bytes.Buffer{}
+			if err := json.Indent(&buf, fields[entity], "", "  "); err != nil {
+				return Manifest{}, nil, err
+			}
+			buf.WriteByte('\n')
+			f.Data = buf.Bytes()
+		}
+		files = append(files, f)
+
+		sum := sha256.Sum256(f.Data)
+		m.Files = append(m.Files, ManifestFile{Name: f.Name, Entity: entity, Count: len(items), SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	bs, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+	files[0].Data = append(bs, '\n')
+	return m, files, nil
+}
+
+// JoinFiles makes JSON backup of files of split backup, checksums of files are verified.
+func JoinFiles(files []File) ([]byte, error) {
+	data := make(map[string][]byte, len(files))
+	for _, f := range files {
+		data[f.Name] = f.Data
+	}
+	bs, ok := data[manifestName]
+	if !ok {
+		return nil, errors.New("no " + manifestName)
+	}
+	var m Manifest
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", manifestName, err)
+	}
+	if m.Format > manifestFormat {
+		return nil, fmt.Errorf("format %d of split backup is not supported, update the app", m.Format)
+	}
+
+	fields := map[string]json.RawMessage{"serverTimestamp": json.RawMessage(strconv.Itoa(m.ServerTimestamp))}
+	for _, mf := range m.Files {
+		if mf.Name == "" {
+			fields[mf.Entity] = json.RawMessage("null")
+			continue
+		}
+		bs, ok := data[mf.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing", mf.Name)
+		}
+		if sum := sha256.Sum256(bs); hex.EncodeToString(sum[:]) != mf.SHA256 {
+			return nil, fmt.Errorf("%s is corrupted, checksum mismatch", mf.Name)
+		}
+		if strings.HasSuffix(mf.Name, ".jsonl") {
+			// compact JSON has no line breaks, so an entity is a line
+			lines := strings.FieldsFunc(string(bs), func(r rune) bool { return r == '\n' })
+			bs = []byte("[" + strings.Join(lines, ",") + "]")
+		}
+		if !json.Valid(bs) {
+			return nil, fmt.Errorf("%s is not valid JSON", mf.Name)
+		}
+		fields[mf.Entity] = bs
+	}
+
+	bs, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return append(bs, '\n'), nil
+}
+
+// untar reverts Split.
+func untar(r io.Reader) (io.Reader, error) {
+	var files []File
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		bs, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: hdr.Name, Data: bs})
+	}
+	bs, err := JoinFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(bs), nil
+}
@@ -0,0 +1,130 @@
+package codec
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const splitBackup = `{"serverTimestamp":1700000000,"account":[{"id":"a1","title":"Main Card"}],"tag":null,` +
+	`"transaction":[{"id":"tx1","payee":"Coffee Shop"},{"id":"tx2","comment":"two\nlines"}]}`
+
+func TestSplitFiles(t *testing.T) {
+	files, err := SplitFiles([]byte(splitBackup), "v1.2.3")
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"manifest.json", "account.json", "transaction.jsonl"}, names, "null tag has no file")
+	assert.Equal(t, "[\n  {\n    \"id\": \"a1\",\n    \"title\": \"Main Card\"\n  }\n]\n", string(files[1].Data))
+	assert.Equal(t, `{"id":"tx1","payee":"Coffee Shop"}`+"\n"+`{"id":"tx2","comment":"two\nlines"}`+"\n", string(files[2].Data))
+
+	var m Manifest
+	assert.NoError(t, json.Unmarshal(files[0].Data, &m))
+	assert.Equal(t, 1, m.Format)
+	assert.Equal(t, "v1.2.3", m.Version)
+	assert.Equal(t, 1700000000, m.ServerTimestamp)
+	sum := sha256.Sum256(files[2].Data)
+	assert.Equal(t, ManifestFile{Name: "transaction.jsonl", Entity: "transaction", Count: 2, SHA256: hex.EncodeToString(sum[:])}, m.Files[2])
+	assert.Equal(t, ManifestFile{Entity: "tag"}, m.Files[1], "tag is null")
+
+	_, err = SplitFiles([]byte("[1]"), "")
+	assert.ErrorContains(t, err, "can't split backup")
+}
+
+func TestJoinFiles(t *testing.T) {
+	files, err := SplitFiles([]byte(splitBackup), "v1.2.3")
+	assert.NoError(t, err)
+	bs, err := JoinFiles(files)
+	assert.NoError(t, err)
+	assert.JSONEq(t, splitBackup, string(bs))
+
+	corrupted := append([]File{}, files...)
+	corrupted[1] = File{Name: "account.json", Data: []byte("[]\n")}
+	_, err = JoinFiles(corrupted)
+	assert.EqualError(t, err, "account.json is corrupted, checksum mismatch")
+
+	_, err = JoinFiles(files[1:])
+	assert.EqualError(t, err, "no manifest.json")
+	_, err = JoinFiles(files[:2])
+	assert.EqualError(t, err, "transaction.jsonl is missing")
+	_, err = JoinFiles([]File{{Name: "manifest.json", Data: []byte(`{"format":2}`)}})
+	assert.EqualError(t, err, "format 2 of split backup is not supported, update the app")
+}
+
+func TestJoinFiles_nullAndEmpty(t *testing.T) {
+	for _, backup := range []string{
+		`{"serverTimestamp":1,"reminderMarker":null,"transaction":[],"tag":null,"account":[]}`,
+		`{"serverTimestamp":1,"reminderMarker":[],"transaction":null,"tag":[],"account":null}`,
+	} {
+		files, err := SplitFiles([]byte(backup), "")
+		assert.NoError(t, err)
+		bs, err := JoinFiles(files)
+		assert.NoError(t, err)
+		assert.JSONEq(t, backup, string(bs))
+	}
+
+	// nil and empty slices of the response survive the round trip
+	v := map[string]any{"serverTimestamp": 1, "transaction": []models.Transaction(nil), "tag": []models.Tag{}}
+	bs, err := json.Marshal(v)
+	assert.NoError(t, err)
+	files, err := SplitFiles(bs, "")
+	assert.NoError(t, err)
+	bs, err = JoinFiles(files)
+	assert.NoError(t, err)
+	var res struct {
+		Transaction []models.Transaction `json:"transaction"`
+		Tag         []models.Tag         `json:"tag"`
+	}
+	assert.NoError(t, json.Unmarshal(bs, &res))
+	assert.Nil(t, res.Transaction)
+	assert.NotNil(t, res.Tag)
+	assert.Empty(t, res.Tag)
+}
+
+func TestSplit(t *testing.T) {
+	c, err := NewCompressor("gzip", 0)
+	assert.NoError(t, err)
+	s := NewSplit("v1.2.3")
+	assert.Equal(t, ".tar.gz", Ext(s, c))
+
+	var v map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(splitBackup), &v))
+	bs, err := Encode(v, s, c)
+	assert.NoError(t, err)
+
+	dec := NewDecoder(nil)
+	res, err := dec.Decode("zen_2024-06-29_15-30-45.json.tar.gz", bs)
+	assert.NoError(t, err)
+	assert.JSONEq(t, splitBackup, string(res))
+	assert.Equal(t, "zen_2024-06-29_15-30-45.json", TrimExt("zen_2024-06-29_15-30-45.json.tar.gz"))
+
+	// the archive is a plain tar with a file per entity
+	plain, err := Encode(v, s)
+	assert.NoError(t, err)
+	tr := tar.NewReader(bytes.NewReader(plain))
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1700000000), hdr.ModTime.Unix())
+		names = append(names, hdr.Name)
+	}
+	assert.Equal(t, []string{"manifest.json", "account.json", "transaction.jsonl"}, names)
+
+	_, err = dec.Decode("zen_2024-06-29_15-30-45.json.tar", []byte("not a tar"))
+	assert.ErrorContains(t, err, "can't decode zen_2024-06-29_15-30-45.json.tar")
+}
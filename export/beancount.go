@@ -0,0 +1,62 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// Beancount writes transactions as Beancount journal: accounts are opened by their types, e.g.
+// Assets:Bank:Card or Liabilities:CreditCard:Visa, categories are Expenses and Income accounts.
+// Conversions between currencies have total price, e.g. -110 USD @@ 100 EUR.
+type Beancount struct{}
+
+// Ext returns extension of Beancount files.
+func (Beancount) Ext() string {
+	return ".beancount"
+}
+
+// Export writes transactions of resp as Beancount journal to w.
+func (Beancount) Export(w io.Writer, resp models.Response) error {
+	j := newJournal(resp)
+	sb := strings.Builder{}
+	sb.WriteString("; ZenMoney transactions\n\n")
+	for _, a := range j.accounts {
+		fmt.Fprintf(&sb, "%s open %s\n", a.open, a.name)
+	}
+	for _, e := range j.entries {
+		sb.WriteString("\n" + e.date + " *")
+		if e.payee != "" {
+			sb.WriteString(" " + beancountString(e.payee))
+		}
+		sb.WriteString(" " + beancountString(e.narration) + "\n")
+		if e.id != "" {
+			fmt.Fprintf(&sb, "  id: %s\n", beancountString(e.id))
+		}
+		if len(e.tags) > 0 {
+			fmt.Fprintf(&sb, "  tags: %s\n", beancountString(strings.Join(e.tags, "; ")))
+		}
+		for _, p := range e.postings {
+			fmt.Fprintf(&sb, "  %s\n", formatPosting(p, j.width))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// beancountString quotes s, strings can't span lines.
+func beancountString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+	return `"` + s + `"`
+}
+
+// formatPosting formats posting as account, amount and price, amounts of all postings are aligned.
+func formatPosting(p posting, width int) string {
+	res := fmt.Sprintf("%-*s  %s %s", width, p.account, formatAmount(p.amount), p.currency)
+	if p.priceCurrency != "" {
+		res += fmt.Sprintf(" @@ %s %s", formatAmount(p.price), p.priceCurrency)
+	}
+	return res
+}
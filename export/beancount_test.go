@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBeancount_Export(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, Beancount{}.Export(&buf, testResponse()))
+	assert.Equal(t, `; ZenMoney transactions
+
+2024-01-15 open Assets:Deposit:Savings-EUR
+2024-02-01 open Assets:Cash:Cash
+2024-02-01 open Equity:Opening-Balances
+2024-03-01 open Expenses:Food:Restaurants
+2024-03-01 open Income:Salary
+2024-03-01 open Liabilities:CreditCard:Card
+2024-03-06 open Expenses:Food
+
+2024-02-01 * "Opening balance"
+  Assets:Cash:Cash             50 USD
+  Equity:Opening-Balances      -50 USD
+
+2024-03-01 * "ACME" ""
+  id: "tx-salary"
+  Assets:Cash:Cash             1000 USD
+  Income:Salary                -1000 USD
+
+2024-03-01 * "Cafe \"Good, Food\"" "lunch with team"
+  id: "tx-cafe"
+  tags: "Business"
+  Liabilities:CreditCard:Card  -12.5 USD
+  Expenses:Food:Restaurants    12.5 USD
+
+2024-03-05 * ""
+  id: "tx-transfer"
+  Liabilities:CreditCard:Card  -110 USD @@ 100 EUR
+  Assets:Deposit:Savings-EUR   100 EUR
+
+2024-03-06 * ""
+  id: "tx-abroad"
+  Liabilities:CreditCard:Card  -11 USD
+  Expenses:Food                10 EUR @@ 11 USD
+`, buf.String())
+	assert.Equal(t, ".beancount", Beancount{}.Ext())
+}
+
+func TestBeancountString(t *testing.T) {
+	assert.Equal(t, `"say \"hi\" \\ bye"`, beancountString(`say "hi" \ bye`))
+	assert.Equal(t, `"two lines"`, beancountString("two\r\nlines"))
+}
+
+func TestBeancount_Check(t *testing.T) {
+	checkJournal(t, Beancount{}.Export, ".beancount", "bean-check")
+}
+
+// checkJournal writes journal of the fixture and validates it by the tool of the format. The test is skipped
+// if the tool is not installed, unless ZENB_TEST_JOURNAL_TOOLS is set, as it is in CI.
+func checkJournal(t *testing.T, export func(io.Writer, models.Response) error, ext, name string, args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath(name); err != nil {
+		if os.Getenv("ZENB_TEST_JOURNAL_TOOLS") != "" {
+			t.Fatalf("%s is required by ZENB_TEST_JOURNAL_TOOLS: %v", name, err)
+		}
+		t.Skipf("%s is not installed", name)
+	}
+	buf := bytes.Buffer{}
+	assert.NoError(t, export(&buf, testResponse()))
+	path := filepath.Join(t.TempDir(), "zen"+ext)
+	assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+
+	out, err := exec.CommandContext(t.Context(), name, append(args, path)...).CombinedOutput() // #nosec G204 - test runs known tools
+	assert.NoError(t, err, string(out))
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+var csvHeader = []string{
+	"date", "type", "account", "amount", "currency", "to_account", "to_amount", "to_currency", "op_amount",
+	"op_currency", "category", "tags", "payee", "comment", "id",
+}
+
+// CSV writes transactions as CSV, one row per transaction. Category is the path of the main tag
+// joined by " / ", other tags are joined by "; ". Operation amount and currency are set if the
+// transaction was made in a currency other than the account one.
+type CSV struct{}
+
+// Ext returns extension of CSV files.
+func (CSV) Ext() string {
+	return ".csv"
+}
+
+// Export writes transactions of resp as CSV with header to w.
+func (CSV) Export(w io.Writer, resp models.Response) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, tx := range Transactions(resp) {
+		var toAmount, opAmount string
+		if tx.ToAccount != "" {
+			toAmount = formatAmount(tx.ToAmount)
+		}
+		if tx.OpCurrency != "" {
+			opAmount = formatAmount(tx.OpAmount)
+		}
+		tags := make([]string, 0, len(tx.Categories))
+		for _, path := range tx.Categories[min(1, len(tx.Categories)):] {
+			tags = append(tags, strings.Join(path, " / "))
+		}
+		row := []string{
+			tx.Date, tx.Type(), tx.Account, formatAmount(tx.Amount), tx.Currency, tx.ToAccount, toAmount, tx.ToCurrency,
+			opAmount, tx.OpCurrency, strings.Join(tx.Category(), " / "), strings.Join(tags, "; "), tx.Payee, tx.Comment, tx.ID,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatAmount(v float64) string {
+	if v == 0 {
+		v = 0 // no "-0" for empty outcome
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,21 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSV_Export(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, CSV{}.Export(&buf, testResponse()))
+	assert.Equal(t, `date,type,account,amount,currency,to_account,to_amount,to_currency,op_amount,op_currency,category,tags,payee,comment,id
+2024-03-01,income,Cash,1000,USD,,,,,,Salary,,ACME,,tx-salary
+2024-03-01,expense,Card,-12.5,USD,,,,,,Food / Restaurants,Business,"Cafe ""Good, Food""","lunch
+with team",tx-cafe
+2024-03-05,transfer,Card,-110,USD,Savings EUR,100,EUR,,,,,,,tx-transfer
+2024-03-06,expense,Card,-11,USD,,,,-10,EUR,Food,,,,tx-abroad
+`, buf.String())
+	assert.Equal(t, ".csv", CSV{}.Ext())
+}
@@ -0,0 +1,211 @@
+// Package export converts ZenMoney data to formats of other tools, e.g. spreadsheets. References
+// between entities (accounts, tags, merchants, instruments) are resolved to their names.
+package export
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// Transaction is a transaction with references resolved to names. Expense and transfer have
+// negative Amount taken from Account, income has positive one put to Account.
+type Transaction struct {
+	ID        string
+	Date      string // yyyy-mm-dd
+	Created   int    // unix timestamp, orders transactions of the same date
+	AccountID string
+	Account   string
+	Amount    float64
+	Currency  string
+
+	// set for transfers only, the account money goes to
+	ToAccountID string
+	ToAccount   string
+	ToAmount    float64
+	ToCurrency  string
+
+	// set if expense or income is made in other currency than the account one, e.g. a purchase abroad,
+	// has the same sign as Amount
+	OpAmount   float64
+	OpCurrency string
+
+	Categories [][]string // paths of tags from the root, e.g. [Food Restaurants], the first one is the main category
+	Payee      string     // merchant title, or payee if there is no merchant
+	Comment    string
+	Hold       bool // pending, not settled by the bank yet
+}
+
+// Type returns "transfer", "income" or "expense".
+func (t Transaction) Type() string {
+	switch {
+	case t.IsTransfer():
+		return "transfer"
+	case t.Amount > 0:
+		return "income"
+	default:
+		return "expense"
+	}
+}
+
+// IsTransfer reports whether money is moved between accounts.
+func (t Transaction) IsTransfer() bool {
+	return t.ToAccountID != ""
+}
+
+// Category returns path of the main category, nil if the transaction has no tags.
+func (t Transaction) Category() []string {
+	if len(t.Categories) == 0 {
+		return nil
+	}
+	return t.Categories[0]
+}
+
+// Transactions returns not deleted transactions of resp ordered by date and creation time.
+func Transactions(resp models.Response) []Transaction {
+	b := newBook(resp)
+	res := make([]Transaction, 0, len(resp.Transaction))
+	for _, tx := range resp.Transaction {
+		if !tx.Deleted {
+			res = append(res, b.transaction(tx))
+		}
+	}
+	slices.SortStableFunc(res, func(a, b Transaction) int {
+		return cmp.Or(cmp.Compare(a.Date, b.Date), cmp.Compare(a.Created, b.Created), cmp.Compare(a.ID, b.ID))
+	})
+	return res
+}
+
+// book indexes entities referenced by transactions.
+type book struct {
+	accounts    map[string]models.Account
+	tags        map[string]models.Tag
+	merchants   map[string]string
+	instruments map[int]string
+}
+
+func newBook(resp models.Response) book {
+	b := book{
+		accounts:    make(map[string]models.Account, len(resp.Account)),
+		tags:        make(map[string]models.Tag, len(resp.Tag)),
+		merchants:   make(map[string]string, len(resp.Merchant)),
+		instruments: make(map[int]string, len(resp.Instrument)),
+	}
+	for _, a := range resp.Account {
+		b.accounts[a.ID] = a
+	}
+	for _, t := range resp.Tag {
+		b.tags[t.ID] = t
+	}
+	for _, m := range resp.Merchant {
+		b.merchants[m.ID] = m.Title
+	}
+	for _, i := range resp.Instrument {
+		b.instruments[i.ID] = i.ShortTitle
+	}
+	return b
+}
+
+func (b book) transaction(tx models.Transaction) Transaction {
+	res := Transaction{ID: tx.ID, Date: tx.Date, Created: tx.Created, Payee: tx.Payee, Hold: tx.Hold}
+	if tx.Comment != nil {
+		res.Comment = *tx.Comment
+	}
+	if tx.Merchant != nil {
+		if title, ok := b.merchants[*tx.Merchant]; ok {
+			res.Payee = title
+		}
+	}
+	for _, id := range tx.Tag {
+		res.Categories = append(res.Categories, b.tagPath(id))
+	}
+
+	outcomeAccount := tx.IncomeAccount
+	if tx.OutcomeAccount != nil {
+		outcomeAccount = *tx.OutcomeAccount
+	}
+	switch {
+	case outcomeAccount != tx.IncomeAccount && tx.Income != 0 && tx.Outcome != 0:
+		res.AccountID, res.Amount, res.Currency = outcomeAccount, -tx.Outcome, b.currency(tx.OutcomeInstrument)
+		res.ToAccountID, res.ToAmount, res.ToCurrency = tx.IncomeAccount, tx.Income, b.currency(tx.IncomeInstrument)
+		res.ToAccount = b.account(tx.IncomeAccount)
+	case tx.Income != 0:
+		// income, or correction of the balance with both sides on the same account, the difference is rounded
+		// as amounts have no more decimal places than the instrument, e.g. 100.1-0.2 is 99.9, not 99.89999999999999
+		res.AccountID, res.Amount, res.Currency = tx.IncomeAccount, round(tx.Income-tx.Outcome), b.currency(tx.IncomeInstrument)
+		if tx.Outcome == 0 {
+			res.OpAmount, res.OpCurrency = b.op(tx.OpIncome, tx.OpIncomeInstrument, tx.IncomeInstrument)
+		}
+	default:
+		res.AccountID, res.Amount, res.Currency = outcomeAccount, -tx.Outcome, b.currency(tx.OutcomeInstrument)
+		res.OpAmount, res.OpCurrency = b.op(-tx.OpOutcome, tx.OpOutcomeInstrument, tx.OutcomeInstrument)
+	}
+	res.Account = b.account(res.AccountID)
+	return res
+}
+
+// op returns amount and currency of operation made in instrument other than the account one.
+func (b book) op(amount float64, instrument *int, accountInstrument int) (float64, string) {
+	if amount == 0 || instrument == nil || *instrument == accountInstrument {
+		return 0, ""
+	}
+	return amount, b.currency(*instrument)
+}
+
+// account returns title of the account, or its id if it's unknown.
+func (b book) account(id string) string {
+	if a, ok := b.accounts[id]; ok {
+		return a.Title
+	}
+	return id
+}
+
+// currency returns code of the instrument, e.g. USD, or its id if it's unknown. It's empty if instrument is not set.
+func (b book) currency(id int) string {
+	if code, ok := b.instruments[id]; ok {
+		return code
+	}
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// tagPath returns titles of the tag and its parents, from the root.
+func (b book) tagPath(id string) []string {
+	var res []string
+	seen := map[string]bool{}
+	for !seen[id] {
+		seen[id] = true
+		t, ok := b.tags[id]
+		if !ok {
+			res = append(res, id)
+			break
+		}
+		res = append(res, t.Title)
+		if t.Parent == nil {
+			break
+		}
+		id = *t.Parent
+	}
+	slices.Reverse(res)
+	return res
+}
+
+// isCreditCard reports whether a is a card with credit limit, rather than a debit one.
+func isCreditCard(a models.Account) bool {
+	return a.Type == "ccard" && a.CreditLimit != nil && *a.CreditLimit > 0
+}
+
+// isLoan reports whether a is a loan or debt, its start balance is the principal.
+func isLoan(a models.Account) bool {
+	return a.Type == "loan" || a.Type == "debt"
+}
+
+// singleLine replaces line breaks and repeated spaces of s with a space.
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
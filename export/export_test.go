@@ -0,0 +1,93 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+func int32Ptr(i int32) *int32 { return &i }
+
+func floatPtr(f float64) *float64 { return &f }
+
+// testResponse returns data with an expense, income, transfer between currencies, expense in other currency
+// and a deleted transaction.
+func testResponse() models.Response {
+	return models.Response{
+		Instrument: []models.Instrument{{ID: 1, ShortTitle: "USD", Symbol: "$"}, {ID: 2, ShortTitle: "EUR", Symbol: "€"}},
+		Account: []models.Account{
+			{ID: "a-card", Title: "Card", Type: "ccard", Instrument: int32Ptr(1), CreditLimit: floatPtr(1000)},
+			{ID: "a-cash", Title: "Cash", Type: "cash", Instrument: int32Ptr(1), StartDate: strPtr("2024-02-01"), StartBalance: floatPtr(50)},
+			{ID: "a-eur", Title: "Savings EUR", Type: "deposit", Instrument: int32Ptr(2), StartDate: strPtr("2024-01-15")},
+		},
+		Tag: []models.Tag{
+			{ID: "t-food", Title: "Food"},
+			{ID: "t-rest", Title: "Restaurants", Parent: strPtr("t-food")},
+			{ID: "t-biz", Title: "Business"},
+			{ID: "t-salary", Title: "Salary"},
+		},
+		Merchant: []models.Merchant{{ID: "m-cafe", Title: "Cafe \"Good, Food\""}},
+		Transaction: []models.Transaction{
+			{ID: "tx-transfer", Date: "2024-03-05", Created: 300, OutcomeAccount: strPtr("a-card"), Outcome: 110, OutcomeInstrument: 1,
+				IncomeAccount: "a-eur", Income: 100, IncomeInstrument: 2},
+			{ID: "tx-cafe", Date: "2024-03-01", Created: 200, OutcomeAccount: strPtr("a-card"), Outcome: 12.5, OutcomeInstrument: 1,
+				IncomeAccount: "a-card", IncomeInstrument: 1, Tag: []string{"t-rest", "t-biz"}, Merchant: strPtr("m-cafe"),
+				Payee: "CAFE GOOD FOOD", Comment: strPtr("lunch\nwith team")},
+			{ID: "tx-salary", Date: "2024-03-01", Created: 100, OutcomeAccount: strPtr("a-cash"), OutcomeInstrument: 1,
+				IncomeAccount: "a-cash", Income: 1000, IncomeInstrument: 1, Tag: []string{"t-salary"}, Payee: "ACME"},
+			{ID: "tx-abroad", Date: "2024-03-06", Created: 400, OutcomeAccount: strPtr("a-card"), Outcome: 11, OutcomeInstrument: 1,
+				IncomeAccount: "a-card", IncomeInstrument: 1, OpOutcome: 10, OpOutcomeInstrument: intPtr(2), Tag: []string{"t-food"}},
+			{ID: "tx-deleted", Date: "2024-03-02", OutcomeAccount: strPtr("a-cash"), Outcome: 1, IncomeAccount: "a-cash", Deleted: true},
+		},
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	res := Transactions(testResponse())
+	assert.Equal(t, []Transaction{
+		{ID: "tx-salary", Date: "2024-03-01", Created: 100, AccountID: "a-cash", Account: "Cash", Amount: 1000, Currency: "USD",
+			Categories: [][]string{{"Salary"}}, Payee: "ACME"},
+		{ID: "tx-cafe", Date: "2024-03-01", Created: 200, AccountID: "a-card", Account: "Card", Amount: -12.5, Currency: "USD",
+			Categories: [][]string{{"Food", "Restaurants"}, {"Business"}}, Payee: "Cafe \"Good, Food\"", Comment: "lunch\nwith team"},
+		{ID: "tx-transfer", Date: "2024-03-05", Created: 300, AccountID: "a-card", Account: "Card", Amount: -110, Currency: "USD",
+			ToAccountID: "a-eur", ToAccount: "Savings EUR", ToAmount: 100, ToCurrency: "EUR"},
+		{ID: "tx-abroad", Date: "2024-03-06", Created: 400, AccountID: "a-card", Account: "Card", Amount: -11, Currency: "USD",
+			OpAmount: -10, OpCurrency: "EUR", Categories: [][]string{{"Food"}}},
+	}, res)
+
+	assert.Equal(t, "income", res[0].Type())
+	assert.Equal(t, "expense", res[1].Type())
+	assert.Equal(t, "transfer", res[2].Type())
+	assert.Equal(t, []string{"Food", "Restaurants"}, res[1].Category())
+	assert.Nil(t, res[2].Category())
+}
+
+func TestTransactions_correction(t *testing.T) {
+	res := Transactions(models.Response{Transaction: []models.Transaction{
+		{ID: "tx", Date: "2024-03-01", IncomeAccount: "a-cash", Income: 100.1, OutcomeAccount: strPtr("a-cash"), Outcome: 0.2},
+	}})
+	if assert.Len(t, res, 1) {
+		assert.Equal(t, 99.9, res[0].Amount)
+	}
+}
+
+func TestTransactions_unknownReferences(t *testing.T) {
+	res := Transactions(models.Response{
+		Tag: []models.Tag{{ID: "t-loop", Title: "Loop", Parent: strPtr("t-loop")}},
+		Transaction: []models.Transaction{
+			{ID: "tx", Date: "2024-03-01", OutcomeAccount: strPtr("a-gone"), Outcome: 5, OutcomeInstrument: 42,
+				IncomeAccount: "a-gone", Tag: []string{"t-gone", "t-loop"}},
+		},
+	})
+	if assert.Len(t, res, 1) {
+		assert.Equal(t, "a-gone", res[0].Account)
+		assert.Equal(t, "a-gone", res[0].AccountID)
+		assert.Equal(t, "42", res[0].Currency)
+		assert.Equal(t, [][]string{{"t-gone"}, {"Loop"}}, res[0].Categories)
+	}
+}
@@ -0,0 +1,244 @@
+package export
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+const (
+	openingBalances = "Equity:Opening-Balances"
+	transferFees    = "Expenses:Transfer-Fees"
+	epoch           = "1970-01-01" // date of accounts which are never used
+)
+
+// journal is a double-entry view of transactions shared by plain-text accounting formats.
+type journal struct {
+	accounts []journalAccount // ordered by opening date and name
+	entries  []journalEntry   // ordered by date
+	width    int              // the longest account name, to align amounts
+}
+
+type journalAccount struct {
+	name string
+	open string // yyyy-mm-dd, not after the first posting
+}
+
+type journalEntry struct {
+	date      string
+	payee     string
+	narration string
+	id        string
+	tags      []string // paths of tags other than the category
+	postings  []posting
+}
+
+type posting struct {
+	account  string
+	amount   float64
+	currency string
+
+	// total price of amount in other currency, for conversions
+	price         float64
+	priceCurrency string
+}
+
+func newJournal(resp models.Response) journal {
+	b := newBook(resp)
+	names := accountNames(resp.Account)
+	account := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		return "Assets:Unknown:" + accountPart(id)
+	}
+
+	var entries []journalEntry
+	for _, tx := range Transactions(resp) {
+		e := journalEntry{date: tx.Date, payee: tx.Payee, narration: tx.Comment, id: tx.ID}
+		for _, path := range tx.Categories[min(1, len(tx.Categories)):] {
+			e.tags = append(e.tags, strings.Join(path, " / "))
+		}
+		from := posting{account: account(tx.AccountID), amount: tx.Amount, currency: commodity(tx.Currency)}
+
+		if tx.IsTransfer() {
+			to := posting{account: account(tx.ToAccountID), amount: tx.ToAmount, currency: commodity(tx.ToCurrency)}
+			e.postings = []posting{from, to}
+			switch {
+			case to.currency != from.currency:
+				e.postings[0].price, e.postings[0].priceCurrency = to.amount, to.currency
+			case round(from.amount+to.amount) != 0:
+				// the rest of money is taken by the bank
+				fee := posting{account: transferFees, amount: -round(from.amount + to.amount), currency: from.currency}
+				e.postings = append(e.postings, fee)
+			}
+			entries = append(entries, e)
+			continue
+		}
+
+		category := "Expenses"
+		if tx.Amount > 0 {
+			category = "Income"
+		}
+		path := tx.Category()
+		if len(path) == 0 {
+			path = []string{"Uncategorized"}
+		}
+		for _, part := range path {
+			category += ":" + accountPart(part)
+		}
+		other := posting{account: category, amount: -tx.Amount, currency: from.currency}
+		if tx.OpCurrency != "" && commodity(tx.OpCurrency) != from.currency {
+			other.amount, other.currency = -tx.OpAmount, commodity(tx.OpCurrency)
+			other.price, other.priceCurrency = math.Abs(tx.Amount), from.currency
+		}
+		e.postings = []posting{from, other}
+		entries = append(entries, e)
+	}
+
+	// accounts are opened at the start date, or before the first use if the date is unknown or later
+	opens := map[string]string{}
+	open := func(name, date string) {
+		if d, ok := opens[name]; !ok || date < d {
+			opens[name] = date
+		}
+	}
+	for _, e := range entries {
+		for _, p := range e.postings {
+			open(p.account, e.date)
+		}
+	}
+	fallback := epoch
+	if len(entries) > 0 {
+		fallback = entries[0].date
+	}
+	var openings []journalEntry
+	for _, a := range resp.Account {
+		name := names[a.ID]
+		if a.StartDate != nil && validDate(*a.StartDate) {
+			open(name, *a.StartDate)
+		}
+		if _, ok := opens[name]; !ok {
+			open(name, fallback)
+		}
+		if a.StartBalance == nil || *a.StartBalance == 0 || isLoan(a) {
+			continue
+		}
+		currency := commodity("")
+		if a.Instrument != nil {
+			currency = commodity(b.currency(int(*a.Instrument)))
+		}
+		open(openingBalances, opens[name])
+		openings = append(openings, journalEntry{date: opens[name], narration: "Opening balance", postings: []posting{
+			{account: name, amount: *a.StartBalance, currency: currency},
+			{account: openingBalances, amount: -*a.StartBalance, currency: currency},
+		}})
+	}
+
+	j := journal{entries: append(openings, entries...)}
+	slices.SortStableFunc(j.entries, func(a, b journalEntry) int { return cmp.Compare(a.date, b.date) })
+	for name, date := range opens {
+		j.accounts = append(j.accounts, journalAccount{name: name, open: date})
+		j.width = max(j.width, utf8.RuneCountInString(name))
+	}
+	slices.SortFunc(j.accounts, func(a, b journalAccount) int {
+		return cmp.Or(cmp.Compare(a.open, b.open), cmp.Compare(a.name, b.name))
+	})
+	return j
+}
+
+// accountNames returns journal names of accounts by their ids, e.g. Assets:Cash:Wallet. Names are
+// grouped by account type and made unique.
+func accountNames(accounts []models.Account) map[string]string {
+	res := make(map[string]string, len(accounts))
+	used := map[string]bool{}
+	for _, a := range accounts {
+		var group string
+		switch a.Type {
+		case "cash":
+			group = "Assets:Cash"
+		case "ccard", "checking":
+			group = "Assets:Bank"
+			if isCreditCard(a) {
+				group = "Liabilities:CreditCard"
+			}
+		case "deposit":
+			group = "Assets:Deposit"
+		case "emoney":
+			group = "Assets:EMoney"
+		case "loan":
+			group = "Liabilities:Loan"
+		case "debt":
+			group = "Liabilities:Debt"
+		default:
+			group = "Assets:Other"
+		}
+		name := group + ":" + accountPart(a.Title)
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s:%s-%d", group, accountPart(a.Title), i)
+		}
+		used[name] = true
+		res[a.ID] = name
+	}
+	return res
+}
+
+// accountPart makes a valid component of account name from title, e.g. "Savings EUR" is Savings-EUR.
+func accountPart(title string) string {
+	var sb strings.Builder
+	sep := false
+	for _, r := range title {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			sep = true
+			continue
+		}
+		if sep && sb.Len() > 0 {
+			sb.WriteByte('-')
+		}
+		sep = false
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 {
+		return "Unnamed"
+	}
+	res := sb.String()
+	r, size := utf8.DecodeRuneInString(res)
+	return string(unicode.ToUpper(r)) + res[size:]
+}
+
+// commodity makes a valid commodity of currency code, e.g. USD. XXX is used if the code is unknown.
+func commodity(code string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		}
+	}
+	res := sb.String()
+	switch {
+	case res == "":
+		return "XXX"
+	case res[0] < 'A':
+		res = "X" + res
+	case len(res) == 1:
+		res += "X"
+	}
+	return res[:min(len(res), 24)]
+}
+
+// round drops floating point errors of sums, amounts have no more than 8 decimal places.
+func round(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
+
+func validDate(s string) bool {
+	_, err := time.Parse(time.DateOnly, s)
+	return err == nil
+}
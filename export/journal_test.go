@@ -0,0 +1,72 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJournal(t *testing.T) {
+	j := newJournal(models.Response{
+		Instrument: []models.Instrument{{ID: 1, ShortTitle: "USD"}, {ID: 2, ShortTitle: "EUR"}},
+		Account: []models.Account{
+			{ID: "a1", Title: "Main", Type: "checking", Instrument: int32Ptr(1), StartDate: strPtr("2024-03-10")},
+			{ID: "a2", Title: "Main", Type: "checking", Instrument: int32Ptr(1)},
+			{ID: "a3", Title: "Mortgage", Type: "loan", Instrument: int32Ptr(1), StartBalance: floatPtr(100000)},
+		},
+		Transaction: []models.Transaction{
+			{ID: "fee", Date: "2024-03-02", OutcomeAccount: strPtr("a1"), Outcome: 10.3, OutcomeInstrument: 1,
+				IncomeAccount: "a2", Income: 10.1, IncomeInstrument: 1},
+			{ID: "refund", Date: "2024-03-03", OutcomeAccount: strPtr("a1"), IncomeAccount: "a1", Income: 11, IncomeInstrument: 1,
+				OpIncome: 10, OpIncomeInstrument: intPtr(2)},
+			{ID: "lost", Date: "2024-03-04", OutcomeAccount: strPtr("a-gone"), IncomeAccount: "a-gone", Outcome: 1},
+		},
+	})
+
+	assert.Equal(t, []journalAccount{
+		{name: "Assets:Bank:Main", open: "2024-03-02"},
+		{name: "Assets:Bank:Main-2", open: "2024-03-02"},
+		{name: "Expenses:Transfer-Fees", open: "2024-03-02"},
+		{name: "Liabilities:Loan:Mortgage", open: "2024-03-02"},
+		{name: "Income:Uncategorized", open: "2024-03-03"},
+		{name: "Assets:Unknown:A-gone", open: "2024-03-04"},
+		{name: "Expenses:Uncategorized", open: "2024-03-04"},
+	}, j.accounts, "accounts are opened before the first use, loans have no opening balance")
+	assert.Equal(t, []journalEntry{
+		{date: "2024-03-02", id: "fee", postings: []posting{
+			{account: "Assets:Bank:Main", amount: -10.3, currency: "USD"},
+			{account: "Assets:Bank:Main-2", amount: 10.1, currency: "USD"},
+			{account: "Expenses:Transfer-Fees", amount: 0.2, currency: "USD"},
+		}},
+		{date: "2024-03-03", id: "refund", postings: []posting{
+			{account: "Assets:Bank:Main", amount: 11, currency: "USD"},
+			{account: "Income:Uncategorized", amount: -10, currency: "EUR", price: 11, priceCurrency: "USD"},
+		}},
+		{date: "2024-03-04", id: "lost", postings: []posting{
+			{account: "Assets:Unknown:A-gone", amount: -1, currency: "XXX"},
+			{account: "Expenses:Uncategorized", amount: 1, currency: "XXX"},
+		}},
+	}, j.entries)
+	assert.Equal(t, 25, j.width)
+}
+
+func TestAccountPart(t *testing.T) {
+	tbl := map[string]string{
+		"Savings EUR":        "Savings-EUR",
+		"  my card (**1234)": "My-card-1234",
+		"наличные":           "Наличные",
+		"2nd":                "2nd",
+		"!!!":                "Unnamed",
+	}
+	for title, want := range tbl {
+		assert.Equal(t, want, accountPart(title), title)
+	}
+}
+
+func TestCommodity(t *testing.T) {
+	tbl := map[string]string{"USD": "USD", "usd": "USD", "": "XXX", "42": "X42", "$": "XXX", "R": "RX", "US$": "US"}
+	for code, want := range tbl {
+		assert.Equal(t, want, commodity(code), code)
+	}
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// Ledger writes transactions as ledger-cli journal, which is read by hledger as well. Accounts and
+// postings are the same as of Beancount, id and other tags are kept as metadata comments.
+type Ledger struct{}
+
+// Ext returns extension of ledger files.
+func (Ledger) Ext() string {
+	return ".ledger"
+}
+
+// Export writes transactions of resp as ledger journal to w.
+func (Ledger) Export(w io.Writer, resp models.Response) error {
+	j := newJournal(resp)
+	sb := strings.Builder{}
+	sb.WriteString("; ZenMoney transactions\n\n")
+	for _, a := range j.accounts {
+		fmt.Fprintf(&sb, "account %s\n", a.name)
+	}
+	for _, e := range j.entries {
+		payee, note := e.payee, e.narration
+		if payee == "" {
+			payee, note = note, ""
+		}
+		sb.WriteString("\n" + e.date + " *")
+		if payee != "" {
+			// description ends at ; in hledger
+			sb.WriteString(" " + strings.ReplaceAll(singleLine(payee), ";", ","))
+		}
+		if note != "" {
+			sb.WriteString("  ; " + singleLine(note))
+		}
+		sb.WriteString("\n")
+		if e.id != "" {
+			fmt.Fprintf(&sb, "    ; id: %s\n", singleLine(e.id))
+		}
+		if len(e.tags) > 0 {
+			fmt.Fprintf(&sb, "    ; tags: %s\n", singleLine(strings.Join(e.tags, ", ")))
+		}
+		for _, p := range e.postings {
+			fmt.Fprintf(&sb, "    %s\n", formatPosting(p, j.width))
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger_Export(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, Ledger{}.Export(&buf, testResponse()))
+	assert.Equal(t, `; ZenMoney transactions
+
+account Assets:Deposit:Savings-EUR
+account Assets:Cash:Cash
+account Equity:Opening-Balances
+account Expenses:Food:Restaurants
+account Income:Salary
+account Liabilities:CreditCard:Card
+account Expenses:Food
+
+2024-02-01 * Opening balance
+    Assets:Cash:Cash             50 USD
+    Equity:Opening-Balances      -50 USD
+
+2024-03-01 * ACME
+    ; id: tx-salary
+    Assets:Cash:Cash             1000 USD
+    Income:Salary                -1000 USD
+
+2024-03-01 * Cafe "Good, Food"  ; lunch with team
+    ; id: tx-cafe
+    ; tags: Business
+    Liabilities:CreditCard:Card  -12.5 USD
+    Expenses:Food:Restaurants    12.5 USD
+
+2024-03-05 *
+    ; id: tx-transfer
+    Liabilities:CreditCard:Card  -110 USD @@ 100 EUR
+    Assets:Deposit:Savings-EUR   100 EUR
+
+2024-03-06 *
+    ; id: tx-abroad
+    Liabilities:CreditCard:Card  -11 USD
+    Expenses:Food                10 EUR @@ 11 USD
+`, buf.String())
+	assert.Equal(t, ".ledger", Ledger{}.Ext())
+}
+
+func TestLedger_ExportComment(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, Ledger{}.Export(&buf, models.Response{Transaction: []models.Transaction{
+		{ID: "tx", Date: "2024-03-01", IncomeAccount: "a", OutcomeAccount: strPtr("a"), Outcome: 1, Comment: strPtr("coffee;\n tea")},
+	}}))
+	assert.Contains(t, buf.String(), "\n2024-03-01 * coffee, tea\n", "comment is the description if there is no payee")
+}
+
+func TestLedger_Check(t *testing.T) {
+	checkJournal(t, Ledger{}.Export, ".ledger", "hledger", "check", "-f")
+}
@@ -0,0 +1,245 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+const (
+	ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n" +
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	ofxBankID  = "ZENMONEY"
+	ofxNameLen = 32 // max length of NAME
+	ofxMemoLen = 255
+)
+
+// OFX writes bank statements of accounts as OFX 2.2 with FITID of transactions set to their ids.
+// Credit cards are in credit card statements, other accounts are in bank ones. Transfers are in
+// statements of both accounts as XFER. Pending (held) transactions are not listed, so their FITIDs are
+// not imported before they settle: ledger balance is the account balance without them, available
+// balance is the full one.
+type OFX struct{}
+
+// Ext returns extension of OFX files.
+func (OFX) Ext() string {
+	return ".ofx"
+}
+
+// Export writes statements of all accounts of resp to w.
+func (OFX) Export(w io.Writer, resp models.Response) error {
+	return writeOFX(w, statements(resp), ofxTime(resp))
+}
+
+// Parts returns statements of accounts of resp, a file per account.
+func (OFX) Parts(resp models.Response) []Part {
+	stmts, now := statements(resp), ofxTime(resp)
+	res := make([]Part, 0, len(stmts))
+	for _, s := range stmts {
+		res = append(res, Part{Name: s.name, Export: func(w io.Writer) error { return writeOFX(w, []statement{s}, now) }})
+	}
+	return res
+}
+
+// ofxTime returns time of the data, formatted as OFX datetime.
+func ofxTime(resp models.Response) string {
+	return time.Unix(int64(resp.ServerTimestamp), 0).UTC().Format("20060102150405")
+}
+
+func writeOFX(w io.Writer, stmts []statement, now string) error {
+	var bank, cards []statement
+	for _, s := range stmts {
+		if isCreditCard(s.account) {
+			cards = append(cards, s)
+		} else {
+			bank = append(bank, s)
+		}
+	}
+
+	x := &ofxWriter{}
+	x.sb.WriteString(ofxHeader)
+	x.open("OFX")
+	x.open("SIGNONMSGSRSV1")
+	x.open("SONRS")
+	x.status()
+	x.elem("DTSERVER", now)
+	x.elem("LANGUAGE", "ENG")
+	x.close("SONRS")
+	x.close("SIGNONMSGSRSV1")
+	if len(bank) > 0 {
+		x.open("BANKMSGSRSV1")
+		for i, s := range bank {
+			x.statement(s, i+1, now, "STMTTRNRS", "STMTRS")
+		}
+		x.close("BANKMSGSRSV1")
+	}
+	if len(cards) > 0 {
+		x.open("CREDITCARDMSGSRSV1")
+		for i, s := range cards {
+			x.statement(s, len(bank)+i+1, now, "CCSTMTTRNRS", "CCSTMTRS")
+		}
+		x.close("CREDITCARDMSGSRSV1")
+	}
+	x.close("OFX")
+	_, err := io.WriteString(w, x.sb.String())
+	return err
+}
+
+// ofxWriter writes indented OFX elements.
+type ofxWriter struct {
+	sb    strings.Builder
+	depth int
+}
+
+func (x *ofxWriter) open(tag string) {
+	fmt.Fprintf(&x.sb, "%s<%s>\n", strings.Repeat("  ", x.depth), tag)
+	x.depth++
+}
+
+func (x *ofxWriter) close(tag string) {
+	x.depth--
+	fmt.Fprintf(&x.sb, "%s</%s>\n", strings.Repeat("  ", x.depth), tag)
+}
+
+func (x *ofxWriter) elem(tag, value string) {
+	fmt.Fprintf(&x.sb, "%s<%s>", strings.Repeat("  ", x.depth), tag)
+	_ = xml.EscapeText(&x.sb, []byte(value)) // strings.Builder never fails
+	fmt.Fprintf(&x.sb, "</%s>\n", tag)
+}
+
+func (x *ofxWriter) status() {
+	x.open("STATUS")
+	x.elem("CODE", "0")
+	x.elem("SEVERITY", "INFO")
+	x.close("STATUS")
+}
+
+func (x *ofxWriter) statement(s statement, uid int, now, trnrs, stmtrs string) {
+	currency := s.currency
+	var posted []statementEntry
+	var held float64
+	for _, e := range s.entries {
+		if currency == "" {
+			currency = e.Currency
+		}
+		if e.Hold {
+			held += e.amount
+			continue
+		}
+		posted = append(posted, e)
+	}
+
+	x.open(trnrs)
+	x.elem("TRNUID", strconv.Itoa(uid))
+	x.status()
+	x.open(stmtrs)
+	x.elem("CURDEF", commodity(currency))
+	x.account(s.account, "ACCTFROM")
+
+	x.open("BANKTRANLIST")
+	start, end := now[:8], now[:8]
+	if len(posted) > 0 {
+		start, end = ofxDate(posted[0].Date), ofxDate(posted[len(posted)-1].Date)
+	}
+	x.elem("DTSTART", start)
+	x.elem("DTEND", end)
+	for _, e := range posted {
+		x.transaction(e)
+	}
+	x.close("BANKTRANLIST")
+
+	x.open("LEDGERBAL")
+	x.elem("BALAMT", formatAmount(round(s.balance()-held)))
+	x.elem("DTASOF", now)
+	x.close("LEDGERBAL")
+	x.open("AVAILBAL")
+	x.elem("BALAMT", formatAmount(s.balance()))
+	x.elem("DTASOF", now)
+	x.close("AVAILBAL")
+	x.close(stmtrs)
+	x.close(trnrs)
+}
+
+func (x *ofxWriter) transaction(e statementEntry) {
+	x.open("STMTTRN")
+	switch {
+	case e.counterpart != nil:
+		x.elem("TRNTYPE", "XFER")
+	case e.amount > 0:
+		x.elem("TRNTYPE", "CREDIT")
+	default:
+		x.elem("TRNTYPE", "DEBIT")
+	}
+	x.elem("DTPOSTED", ofxDate(e.Date))
+	x.elem("TRNAMT", formatAmount(e.amount))
+	x.elem("FITID", e.ID)
+	name := e.Payee
+	if name == "" && e.counterpart != nil {
+		name = "Transfer: " + e.counterpartName
+	}
+	if name != "" {
+		x.elem("NAME", truncate(singleLine(name), ofxNameLen))
+	}
+	if e.counterpart != nil {
+		x.account(*e.counterpart, "ACCTTO")
+	}
+	var memo []string
+	if c := e.Category(); len(c) > 0 {
+		memo = append(memo, strings.Join(c, " / "))
+	}
+	if e.Comment != "" {
+		memo = append(memo, singleLine(e.Comment))
+	}
+	if len(memo) > 0 {
+		x.elem("MEMO", truncate(strings.Join(memo, ". "), ofxMemoLen))
+	}
+	if e.OpCurrency != "" && e.OpAmount != 0 {
+		// amount is in the statement currency, converted from the original one
+		x.open("ORIGCURRENCY")
+		x.elem("CURRATE", strconv.FormatFloat(round(math.Abs(e.amount/e.OpAmount)), 'f', -1, 64))
+		x.elem("CURSYM", commodity(e.OpCurrency))
+		x.close("ORIGCURRENCY")
+	}
+	x.close("STMTTRN")
+}
+
+// account writes BANKACCTFROM/CCACCTFROM or BANKACCTTO/CCACCTTO aggregate of a.
+func (x *ofxWriter) account(a models.Account, suffix string) {
+	if isCreditCard(a) {
+		x.open("CC" + suffix)
+		x.elem("ACCTID", a.ID)
+		x.close("CC" + suffix)
+		return
+	}
+	x.open("BANK" + suffix)
+	x.elem("BANKID", ofxBankID)
+	x.elem("ACCTID", a.ID)
+	switch {
+	case a.Type == "deposit":
+		x.elem("ACCTTYPE", "SAVINGS")
+	case isLoan(a):
+		x.elem("ACCTTYPE", "CREDITLINE")
+	default:
+		x.elem("ACCTTYPE", "CHECKING")
+	}
+	x.close("BANK" + suffix)
+}
+
+// ofxDate converts yyyy-mm-dd to OFX date.
+func ofxDate(date string) string {
+	return strings.ReplaceAll(date, "-", "")
+}
+
+// truncate cuts s to n runes.
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOFX_Export(t *testing.T) {
+	resp := statementResponse()
+	resp.Account = resp.Account[:1]
+	buf := bytes.Buffer{}
+	assert.NoError(t, OFX{}.Export(&buf, resp))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <SIGNONMSGSRSV1>
+    <SONRS>
+      <STATUS>
+        <CODE>0</CODE>
+        <SEVERITY>INFO</SEVERITY>
+      </STATUS>
+      <DTSERVER>20240308000000</DTSERVER>
+      <LANGUAGE>ENG</LANGUAGE>
+    </SONRS>
+  </SIGNONMSGSRSV1>
+  <BANKMSGSRSV1>
+    <STMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <STATUS>
+        <CODE>0</CODE>
+        <SEVERITY>INFO</SEVERITY>
+      </STATUS>
+      <STMTRS>
+        <CURDEF>USD</CURDEF>
+        <BANKACCTFROM>
+          <BANKID>ZENMONEY</BANKID>
+          <ACCTID>a-main</ACCTID>
+          <ACCTTYPE>CHECKING</ACCTTYPE>
+        </BANKACCTFROM>
+        <BANKTRANLIST>
+          <DTSTART>20240302</DTSTART>
+          <DTEND>20240303</DTEND>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20240302</DTPOSTED>
+            <TRNAMT>-14.5</TRNAMT>
+            <FITID>tx-shop</FITID>
+            <NAME>Shop &amp; Co</NAME>
+            <MEMO>Food: groceries</MEMO>
+          </STMTTRN>
+          <STMTTRN>
+            <TRNTYPE>XFER</TRNTYPE>
+            <DTPOSTED>20240303</DTPOSTED>
+            <TRNAMT>-100</TRNAMT>
+            <FITID>tx-out</FITID>
+            <NAME>Transfer: a-gone</NAME>
+            <BANKACCTTO>
+              <BANKID>ZENMONEY</BANKID>
+              <ACCTID>a-gone</ACCTID>
+              <ACCTTYPE>CHECKING</ACCTTYPE>
+            </BANKACCTTO>
+            <MEMO>rent</MEMO>
+          </STMTTRN>
+        </BANKTRANLIST>
+        <LEDGERBAL>
+          <BALAMT>85.5</BALAMT>
+          <DTASOF>20240308000000</DTASOF>
+        </LEDGERBAL>
+        <AVAILBAL>
+          <BALAMT>75.5</BALAMT>
+          <DTASOF>20240308000000</DTASOF>
+        </AVAILBAL>
+      </STMTRS>
+    </STMTTRNRS>
+  </BANKMSGSRSV1>
+</OFX>
+`, buf.String(), "held transaction is not listed and is excluded from ledger balance")
+	assert.Equal(t, ".ofx", OFX{}.Ext())
+}
+
+func TestOFX_Parts(t *testing.T) {
+	parts := OFX{}.Parts(testResponse())
+	if !assert.Len(t, parts, 3) {
+		return
+	}
+	assert.Equal(t, []string{"Card", "Cash", "Savings-EUR"}, []string{parts[0].Name, parts[1].Name, parts[2].Name})
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, parts[0].Export(&buf))
+	var doc struct {
+		Cards []struct {
+			Currency     string   `xml:"CCSTMTRS>CURDEF"`
+			Account      string   `xml:"CCSTMTRS>CCACCTFROM>ACCTID"`
+			FITIDs       []string `xml:"CCSTMTRS>BANKTRANLIST>STMTTRN>FITID"`
+			OrigCurrency []string `xml:"CCSTMTRS>BANKTRANLIST>STMTTRN>ORIGCURRENCY>CURSYM"`
+			Rate         []string `xml:"CCSTMTRS>BANKTRANLIST>STMTTRN>ORIGCURRENCY>CURRATE"`
+		} `xml:"CREDITCARDMSGSRSV1>CCSTMTTRNRS"`
+		Banks []struct{} `xml:"BANKMSGSRSV1>STMTTRNRS"`
+	}
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Empty(t, doc.Banks, "a statement per file")
+	if assert.Len(t, doc.Cards, 1) {
+		assert.Equal(t, "USD", doc.Cards[0].Currency)
+		assert.Equal(t, "a-card", doc.Cards[0].Account)
+		assert.Equal(t, []string{"tx-cafe", "tx-transfer", "tx-abroad"}, doc.Cards[0].FITIDs)
+		assert.Equal(t, []string{"EUR"}, doc.Cards[0].OrigCurrency)
+		assert.Equal(t, []string{"1.1"}, doc.Cards[0].Rate)
+	}
+}
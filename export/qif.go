@@ -0,0 +1,111 @@
+package export
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// QIF writes transactions of accounts as QIF, an account header with the current balance followed
+// by its transactions. Categories are joined by ":" as QIF subcategories, transfers refer to the other
+// account as [Title]. Settled transactions are cleared, pending (held) ones are not.
+type QIF struct{}
+
+// Ext returns extension of QIF files.
+func (QIF) Ext() string {
+	return ".qif"
+}
+
+// Export writes transactions of all accounts of resp to w.
+func (QIF) Export(w io.Writer, resp models.Response) error {
+	sb := strings.Builder{}
+	for _, s := range statements(resp) {
+		writeQIF(&sb, s, resp)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Parts returns transactions of accounts of resp, a file per account.
+func (QIF) Parts(resp models.Response) []Part {
+	stmts := statements(resp)
+	res := make([]Part, 0, len(stmts))
+	for _, s := range stmts {
+		res = append(res, Part{Name: s.name, Export: func(w io.Writer) error {
+			sb := strings.Builder{}
+			writeQIF(&sb, s, resp)
+			_, err := io.WriteString(w, sb.String())
+			return err
+		}})
+	}
+	return res
+}
+
+func writeQIF(sb *strings.Builder, s statement, resp models.Response) {
+	typ := qifType(s.account)
+	sb.WriteString("!Account\n")
+	sb.WriteString("N" + singleLine(s.account.Title) + "\n")
+	sb.WriteString("T" + typ + "\n")
+	if s.account.CreditLimit != nil && *s.account.CreditLimit > 0 {
+		sb.WriteString("L" + formatAmount(*s.account.CreditLimit) + "\n")
+	}
+	if resp.ServerTimestamp > 0 {
+		sb.WriteString("/" + time.Unix(int64(resp.ServerTimestamp), 0).UTC().Format(qifDateLayout) + "\n")
+	}
+	sb.WriteString("$" + formatAmount(s.balance()) + "\n")
+	sb.WriteString("^\n")
+
+	sb.WriteString("!Type:" + typ + "\n")
+	for _, e := range s.entries {
+		sb.WriteString("D" + qifDate(e.Date) + "\n")
+		sb.WriteString("T" + formatAmount(e.amount) + "\n")
+		if !e.Hold {
+			sb.WriteString("C*\n")
+		}
+		if e.Payee != "" {
+			sb.WriteString("P" + singleLine(e.Payee) + "\n")
+		}
+		if e.Comment != "" {
+			sb.WriteString("M" + singleLine(e.Comment) + "\n")
+		}
+		switch {
+		case e.counterpart != nil:
+			sb.WriteString("L[" + singleLine(e.counterpartName) + "]\n")
+		case len(e.Category()) > 0:
+			parts := make([]string, 0, len(e.Category()))
+			for _, p := range e.Category() {
+				// : and / separate subcategories and classes
+				parts = append(parts, singleLine(strings.NewReplacer(":", " ", "/", " ").Replace(p)))
+			}
+			sb.WriteString("L" + strings.Join(parts, ":") + "\n")
+		}
+		sb.WriteString("^\n")
+	}
+}
+
+// qifType returns QIF type of account.
+func qifType(a models.Account) string {
+	switch {
+	case a.Type == "cash":
+		return "Cash"
+	case isCreditCard(a):
+		return "CCard"
+	case isLoan(a):
+		return "Oth L"
+	default:
+		return "Bank"
+	}
+}
+
+const qifDateLayout = "01/02/2006"
+
+// qifDate converts yyyy-mm-dd to MM/DD/YYYY.
+func qifDate(date string) string {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return date
+	}
+	return t.Format(qifDateLayout)
+}
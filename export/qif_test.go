@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQIF_Export(t *testing.T) {
+	buf := bytes.Buffer{}
+	assert.NoError(t, QIF{}.Export(&buf, statementResponse()))
+	assert.Equal(t, `!Account
+NMain <Bank>
+TBank
+/03/08/2024
+$75.5
+^
+!Type:Bank
+D03/02/2024
+T-14.5
+C*
+PShop & Co
+LFood groceries
+^
+D03/03/2024
+T-100
+C*
+Mrent
+L[a-gone]
+^
+D03/07/2024
+T-10
+PTaxi
+^
+!Account
+NMain Bank
+TCash
+/03/08/2024
+$0
+^
+!Type:Cash
+`, buf.String())
+	assert.Equal(t, ".qif", QIF{}.Ext())
+}
+
+func TestQIF_Parts(t *testing.T) {
+	parts := QIF{}.Parts(testResponse())
+	if !assert.Len(t, parts, 3) {
+		return
+	}
+	assert.Equal(t, "Card", parts[0].Name)
+	buf := bytes.Buffer{}
+	assert.NoError(t, parts[2].Export(&buf))
+	assert.Equal(t, `!Account
+NSavings EUR
+TBank
+$0
+^
+!Type:Bank
+D03/05/2024
+T100
+C*
+L[Card]
+^
+`, buf.String())
+}
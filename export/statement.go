@@ -0,0 +1,84 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// Part is a file of export split by accounts, e.g. statement of an account.
+type Part struct {
+	Name   string // unique name for file name, e.g. Savings-EUR
+	Export func(w io.Writer) error
+}
+
+// statement is transactions of an account from its side, shared by bank statement formats.
+type statement struct {
+	account  models.Account
+	name     string // unique part name
+	currency string
+	entries  []statementEntry // ordered by date
+}
+
+type statementEntry struct {
+	Transaction
+	amount float64 // positive money came to the account, negative one left it
+
+	// set for transfers, the other account, only its id is known if it's not in the data
+	counterpart     *models.Account
+	counterpartName string
+}
+
+// balance returns current balance of the account.
+func (s statement) balance() float64 {
+	if s.account.Balance == nil {
+		return 0
+	}
+	return *s.account.Balance
+}
+
+// statements returns statements of all accounts of resp. Transfers are in statements of both accounts.
+func statements(resp models.Response) []statement {
+	b := newBook(resp)
+	res := make([]statement, 0, len(resp.Account))
+	idx := make(map[string]int, len(resp.Account))
+	used := map[string]bool{}
+	for i, a := range resp.Account {
+		name := accountPart(a.Title)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", accountPart(a.Title), n)
+		}
+		used[name] = true
+		s := statement{account: a, name: name}
+		if a.Instrument != nil {
+			s.currency = b.currency(int(*a.Instrument))
+		}
+		res = append(res, s)
+		idx[a.ID] = i
+	}
+
+	account := func(id string) *models.Account {
+		if i, ok := idx[id]; ok {
+			return &resp.Account[i]
+		}
+		return &models.Account{ID: id}
+	}
+	for _, tx := range Transactions(resp) {
+		if i, ok := idx[tx.AccountID]; ok {
+			e := statementEntry{Transaction: tx, amount: tx.Amount}
+			if tx.IsTransfer() {
+				e.counterpart, e.counterpartName = account(tx.ToAccountID), tx.ToAccount
+			}
+			res[i].entries = append(res[i].entries, e)
+		}
+		if i, ok := idx[tx.ToAccountID]; ok && tx.IsTransfer() {
+			in := tx
+			in.Currency = tx.ToCurrency
+			res[i].entries = append(res[i].entries, statementEntry{
+				Transaction: in, amount: tx.ToAmount, counterpart: account(tx.AccountID), counterpartName: tx.Account,
+			})
+		}
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// statementResponse returns data of a checking account with a settled and a held expense, and a
+// transfer to an account missing in the data.
+func statementResponse() models.Response {
+	return models.Response{
+		ServerTimestamp: 1709856000, // 2024-03-08
+		Instrument:      []models.Instrument{{ID: 1, ShortTitle: "USD"}},
+		Account: []models.Account{
+			{ID: "a-main", Title: "Main <Bank>", Type: "checking", Instrument: int32Ptr(1), Balance: floatPtr(75.5)},
+			{ID: "a-main-2", Title: "Main Bank", Type: "cash", Instrument: int32Ptr(1)},
+		},
+		Tag: []models.Tag{{ID: "t-food", Title: "Food: groceries"}},
+		Transaction: []models.Transaction{
+			{ID: "tx-shop", Date: "2024-03-02", OutcomeAccount: strPtr("a-main"), Outcome: 14.5, OutcomeInstrument: 1,
+				IncomeAccount: "a-main", IncomeInstrument: 1, Payee: "Shop & Co", Tag: []string{"t-food"}},
+			{ID: "tx-held", Date: "2024-03-07", OutcomeAccount: strPtr("a-main"), Outcome: 10, OutcomeInstrument: 1,
+				IncomeAccount: "a-main", IncomeInstrument: 1, Payee: "Taxi", Hold: true},
+			{ID: "tx-out", Date: "2024-03-03", OutcomeAccount: strPtr("a-main"), Outcome: 100, OutcomeInstrument: 1,
+				IncomeAccount: "a-gone", Income: 100, IncomeInstrument: 1, Comment: strPtr("rent")},
+			{ID: "tx-deleted", Date: "2024-03-04", OutcomeAccount: strPtr("a-main"), Outcome: 1, IncomeAccount: "a-main", Deleted: true},
+		},
+	}
+}
+
+func TestStatements(t *testing.T) {
+	res := statements(statementResponse())
+	if !assert.Len(t, res, 2) {
+		return
+	}
+	assert.Equal(t, "Main-Bank", res[0].name)
+	assert.Equal(t, "Main-Bank-2", res[1].name, "names are unique")
+	assert.Equal(t, "USD", res[0].currency)
+	assert.InDelta(t, 75.5, res[0].balance(), 0)
+	assert.Zero(t, res[1].balance())
+	assert.Empty(t, res[1].entries)
+
+	if assert.Len(t, res[0].entries, 3, "deleted transactions are skipped") {
+		assert.Equal(t, "tx-shop", res[0].entries[0].ID)
+		assert.Nil(t, res[0].entries[0].counterpart)
+		assert.Equal(t, "tx-out", res[0].entries[1].ID)
+		assert.Equal(t, &models.Account{ID: "a-gone"}, res[0].entries[1].counterpart)
+		assert.Equal(t, "a-gone", res[0].entries[1].counterpartName)
+		assert.True(t, res[0].entries[2].Hold)
+	}
+
+	res = statements(testResponse())
+	if assert.Len(t, res, 3) && assert.Len(t, res[2].entries, 1) {
+		e := res[2].entries[0]
+		assert.Equal(t, "tx-transfer", e.ID, "transfer is in statements of both accounts")
+		assert.InDelta(t, 100, e.amount, 0)
+		assert.Equal(t, "EUR", e.Currency)
+		assert.Equal(t, "a-card", e.counterpart.ID)
+		assert.Equal(t, "Card", e.counterpartName)
+	}
+}
@@ -0,0 +1,600 @@
+// Package mirror keeps a queryable copy of ZenMoney data in sync with backups.
+package mirror
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	_ "github.com/ncruces/go-sqlite3/driver" // pure Go SQLite, no cgo
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// schema of the database. Columns are named after fields of ZenMoney entities in snake case, lists of
+// tags are in link tables ordered by position, the first tag of a transaction is its category. Foreign
+// keys are enforced for link tables only. References between entities are noted in comments, they have
+// no foreign keys as they can dangle: transactions refer to accounts of other users, deleted merchants
+// and tags, the budget of all categories has an empty tag, and a delta applied before the first full backup
+// refers to rows the mirror hasn't got.
+const schema = `
+CREATE TABLE IF NOT EXISTS instruments (
+	id          INTEGER PRIMARY KEY,
+	title       TEXT NOT NULL,
+	short_title TEXT NOT NULL,
+	symbol      TEXT NOT NULL,
+	rate        REAL NOT NULL,
+	changed     INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS companies (
+	id           INTEGER PRIMARY KEY,
+	title        TEXT NOT NULL,
+	full_title   TEXT NOT NULL,
+	www          TEXT NOT NULL,
+	country      INTEGER NOT NULL,
+	country_code TEXT NOT NULL,
+	deleted      INTEGER NOT NULL,
+	changed      INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS users (
+	id                        INTEGER PRIMARY KEY,
+	login                     TEXT NOT NULL,
+	email                     TEXT NOT NULL,
+	parent                    INTEGER, -- users.id
+	country                   INTEGER NOT NULL,
+	country_code              TEXT NOT NULL,
+	currency                  INTEGER, -- instruments.id
+	paid_till                 INTEGER NOT NULL,
+	month_start_day           INTEGER NOT NULL,
+	is_forecast_enabled       INTEGER NOT NULL,
+	plan_balance_mode         TEXT NOT NULL,
+	plan_settings             TEXT NOT NULL,
+	subscription              TEXT NOT NULL,
+	subscription_renewal_date INTEGER,
+	changed                   INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS accounts (
+	id                       TEXT PRIMARY KEY,
+	user                     INTEGER NOT NULL, -- users.id
+	instrument               INTEGER, -- instruments.id
+	type                     TEXT NOT NULL,
+	role                     INTEGER,
+	private                  INTEGER NOT NULL,
+	savings                  INTEGER,
+	title                    TEXT NOT NULL,
+	in_balance               INTEGER NOT NULL,
+	credit_limit             REAL,
+	start_balance            REAL,
+	balance                  REAL,
+	company                  INTEGER, -- companies.id
+	archive                  INTEGER NOT NULL,
+	enable_correction        INTEGER NOT NULL,
+	balance_correction_type  TEXT NOT NULL,
+	start_date               TEXT,
+	capitalization           INTEGER,
+	percent                  REAL,
+	sync_id                  TEXT, -- JSON array
+	enable_sms               INTEGER NOT NULL,
+	end_date_offset          INTEGER,
+	end_date_offset_interval TEXT,
+	payoff_step              INTEGER,
+	payoff_interval          TEXT,
+	changed                  INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tags (
+	id             TEXT PRIMARY KEY,
+	user           INTEGER NOT NULL, -- users.id
+	title          TEXT NOT NULL,
+	parent         TEXT, -- tags.id
+	icon           TEXT,
+	picture        TEXT,
+	color          INTEGER,
+	show_income    INTEGER NOT NULL,
+	show_outcome   INTEGER NOT NULL,
+	budget_income  INTEGER NOT NULL,
+	budget_outcome INTEGER NOT NULL,
+	required       INTEGER,
+	static_id      TEXT NOT NULL,
+	changed        INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS merchants (
+	id      TEXT PRIMARY KEY,
+	user    INTEGER NOT NULL, -- users.id
+	title   TEXT NOT NULL,
+	changed INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS budgets (
+	user                INTEGER NOT NULL, -- users.id
+	tag                 TEXT NOT NULL, -- tags.id, '' if not set
+	date                TEXT NOT NULL,
+	income              REAL NOT NULL,
+	outcome             REAL NOT NULL,
+	income_lock         INTEGER NOT NULL,
+	outcome_lock        INTEGER NOT NULL,
+	is_income_forecast  INTEGER NOT NULL,
+	is_outcome_forecast INTEGER NOT NULL,
+	changed             INTEGER NOT NULL,
+	PRIMARY KEY (user, tag, date)
+);
+CREATE TABLE IF NOT EXISTS reminders (
+	id                 TEXT PRIMARY KEY,
+	user               INTEGER NOT NULL, -- users.id
+	income             REAL NOT NULL,
+	outcome            REAL NOT NULL,
+	income_instrument  INTEGER NOT NULL, -- instruments.id
+	outcome_instrument INTEGER NOT NULL, -- instruments.id
+	income_account     TEXT NOT NULL, -- accounts.id
+	outcome_account    TEXT NOT NULL, -- accounts.id
+	step               INTEGER NOT NULL,
+	points             TEXT, -- JSON array
+	start_date         TEXT NOT NULL,
+	end_date           TEXT,
+	interval           TEXT,
+	notify             INTEGER NOT NULL,
+	comment            TEXT NOT NULL,
+	payee              TEXT,
+	merchant           TEXT, -- merchants.id
+	changed            INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS reminder_markers (
+	id                 TEXT PRIMARY KEY,
+	user               INTEGER NOT NULL, -- users.id
+	reminder           TEXT NOT NULL, -- reminders.id
+	date               TEXT NOT NULL,
+	income             REAL NOT NULL,
+	outcome            REAL NOT NULL,
+	income_instrument  INTEGER NOT NULL, -- instruments.id
+	outcome_instrument INTEGER NOT NULL, -- instruments.id
+	income_account     TEXT NOT NULL, -- accounts.id
+	outcome_account    TEXT NOT NULL, -- accounts.id
+	state              TEXT NOT NULL,
+	is_forecast        INTEGER NOT NULL,
+	notify             INTEGER NOT NULL,
+	comment            TEXT NOT NULL,
+	payee              TEXT,
+	merchant           TEXT, -- merchants.id
+	changed            INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS transactions (
+	id                    TEXT PRIMARY KEY,
+	user                  INTEGER NOT NULL, -- users.id
+	date                  TEXT NOT NULL,
+	income                REAL NOT NULL,
+	outcome               REAL NOT NULL,
+	income_instrument     INTEGER NOT NULL, -- instruments.id
+	outcome_instrument    INTEGER NOT NULL, -- instruments.id
+	income_account        TEXT NOT NULL, -- accounts.id
+	outcome_account       TEXT, -- accounts.id
+	op_income             REAL NOT NULL,
+	op_outcome            REAL NOT NULL,
+	op_income_instrument  INTEGER, -- instruments.id
+	op_outcome_instrument INTEGER, -- instruments.id
+	payee                 TEXT NOT NULL,
+	original_payee        TEXT NOT NULL,
+	merchant              TEXT, -- merchants.id
+	comment               TEXT,
+	deleted               INTEGER NOT NULL,
+	viewed                INTEGER NOT NULL,
+	hold                  INTEGER NOT NULL,
+	qr_code               TEXT,
+	source                TEXT NOT NULL,
+	latitude              REAL,
+	longitude             REAL,
+	income_bank_id        TEXT,
+	outcome_bank_id       TEXT,
+	reminder_marker       TEXT, -- reminder_markers.id
+	created               INTEGER NOT NULL,
+	changed               INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS transactions_date ON transactions (date);
+CREATE TABLE IF NOT EXISTS transaction_tags (
+	transaction_id TEXT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+	tag_id         TEXT NOT NULL, -- tags.id
+	position       INTEGER NOT NULL,
+	PRIMARY KEY (transaction_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS transaction_tags_tag ON transaction_tags (tag_id);
+CREATE TABLE IF NOT EXISTS reminder_tags (
+	reminder_id TEXT NOT NULL REFERENCES reminders(id) ON DELETE CASCADE,
+	tag_id      TEXT NOT NULL, -- tags.id
+	position    INTEGER NOT NULL,
+	PRIMARY KEY (reminder_id, tag_id)
+);
+CREATE TABLE IF NOT EXISTS reminder_marker_tags (
+	reminder_marker_id TEXT NOT NULL REFERENCES reminder_markers(id) ON DELETE CASCADE,
+	tag_id             TEXT NOT NULL, -- tags.id
+	position           INTEGER NOT NULL,
+	PRIMARY KEY (reminder_marker_id, tag_id)
+);
+CREATE TABLE IF NOT EXISTS deletions (
+	object TEXT NOT NULL,
+	id     TEXT NOT NULL,
+	user   INTEGER NOT NULL, -- users.id
+	stamp  INTEGER NOT NULL,
+	PRIMARY KEY (object, id)
+);
+`
+
+// table is a table of entities, see schema.
+type table struct {
+	name    string
+	entity  string   // object of models.Deletion
+	key     []string // primary key
+	columns []string // other columns, changed is the last one
+	owned   bool     // rows have user column and are owned by users
+
+	links   string // link table of tags, if entities have them
+	linkKey string // column of entity id in the link table
+}
+
+var (
+	instruments = table{name: "instruments", entity: "instrument", key: []string{"id"},
+		columns: []string{"title", "short_title", "symbol", "rate", "changed"}}
+	companies = table{name: "companies", entity: "company", key: []string{"id"},
+		columns: []string{"title", "full_title", "www", "country", "country_code", "deleted", "changed"}}
+	users = table{name: "users", entity: "user", key: []string{"id"},
+		columns: []string{"login", "email", "parent", "country", "country_code", "currency", "paid_till",
+			"month_start_day", "is_forecast_enabled", "plan_balance_mode", "plan_settings", "subscription",
+			"subscription_renewal_date", "changed"}}
+	accounts = table{name: "accounts", entity: "account", key: []string{"id"}, owned: true,
+		columns: []string{"user", "instrument", "type", "role", "private", "savings", "title", "in_balance",
+			"credit_limit", "start_balance", "balance", "company", "archive", "enable_correction",
+			"balance_correction_type", "start_date", "capitalization", "percent", "sync_id", "enable_sms",
+			"end_date_offset", "end_date_offset_interval", "payoff_step", "payoff_interval", "changed"}}
+	tags = table{name: "tags", entity: "tag", key: []string{"id"}, owned: true,
+		columns: []string{"user", "title", "parent", "icon", "picture", "color", "show_income", "show_outcome",
+			"budget_income", "budget_outcome", "required", "static_id", "changed"}}
+	merchants = table{name: "merchants", entity: "merchant", key: []string{"id"}, owned: true,
+		columns: []string{"user", "title", "changed"}}
+	budgets = table{name: "budgets", entity: "budget", key: []string{"user", "tag", "date"}, owned: true,
+		columns: []string{"income", "outcome", "income_lock", "outcome_lock", "is_income_forecast",
+			"is_outcome_forecast", "changed"}}
+	reminders = table{name: "reminders", entity: "reminder", key: []string{"id"}, owned: true,
+		columns: []string{"user", "income", "outcome", "income_instrument", "outcome_instrument",
+			"income_account", "outcome_account", "step", "points", "start_date", "end_date", "interval",
+			"notify", "comment", "payee", "merchant", "changed"},
+		links: "reminder_tags", linkKey: "reminder_id"}
+	reminderMarkers = table{name: "reminder_markers", entity: "reminderMarker", key: []string{"id"}, owned: true,
+		columns: []string{"user", "reminder", "date", "income", "outcome", "income_instrument",
+			"outcome_instrument", "income_account", "outcome_account", "state", "is_forecast", "notify",
+			"comment", "payee", "merchant", "changed"},
+		links: "reminder_marker_tags", linkKey: "reminder_marker_id"}
+	transactions = table{name: "transactions", entity: "transaction", key: []string{"id"}, owned: true,
+		columns: []string{"user", "date", "income", "outcome", "income_instrument", "outcome_instrument",
+			"income_account", "outcome_account", "op_income", "op_outcome", "op_income_instrument",
+			"op_outcome_instrument", "payee", "original_payee", "merchant", "comment", "deleted", "viewed",
+			"hold", "qr_code", "source", "latitude", "longitude", "income_bank_id", "outcome_bank_id",
+			"reminder_marker", "created", "changed"},
+		links: "transaction_tags", linkKey: "transaction_id"}
+
+	// tables in order of writes, parents go first
+	tables = []table{instruments, companies, users, accounts, tags, merchants, budgets, reminders,
+		reminderMarkers, transactions}
+)
+
+// upsert returns statement inserting a row, or updating it if the row isn't newer by changed stamp.
+func (t table) upsert() string {
+	cols := append(append([]string{}, t.key...), t.columns...)
+	set := make([]string, 0, len(t.columns))
+	for _, c := range t.columns {
+		set = append(set, fmt.Sprintf("%s = excluded.%s", c, c))
+	}
+	// #nosec G201 - names of tables and columns are constants
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s WHERE excluded.changed >= %s.changed",
+		t.name, strings.Join(cols, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", "),
+		strings.Join(t.key, ", "), strings.Join(set, ", "), t.name)
+}
+
+// SQLite is a mirror of ZenMoney data in SQLite database, see Apply. The database can be read by
+// other tools while it's updated, e.g. by Grafana.
+type SQLite struct {
+	path string
+}
+
+// NewSQLite makes SQLite mirror in the database file, it's created by the first Apply.
+func NewSQLite(path string) *SQLite {
+	return &SQLite{path: path}
+}
+
+// Path returns path of the database file.
+func (s *SQLite) Path() string {
+	return s.path
+}
+
+// Apply writes entities of resp to the database in a single transaction. Rows are upserted by ids,
+// a row is kept if it has a newer changed stamp than the entity. Deletions remove rows and are saved
+// to deletions table. resp of full sync replaces all data of its users: rows it doesn't have are deleted.
+func (s *SQLite) Apply(ctx context.Context, resp models.Response, full bool) (err error) {
+	db, err := sql.Open("sqlite3", s.dsn())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := db.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("can't create schema of %s: %w", s.path, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	w := &writer{tx: tx, keys: map[string]map[string]bool{}}
+	if err := w.write(ctx, resp, full); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("can't update %s: %w", s.path, err)
+	}
+	return tx.Commit()
+}
+
+// dsn returns data source name of the database with foreign keys enforced.
+func (s *SQLite) dsn() string {
+	return "file:" + (&url.URL{Path: s.path}).EscapedPath() + "?_pragma=busy_timeout(10000)&_pragma=foreign_keys(1)"
+}
+
+// writer writes entities in a transaction.
+type writer struct {
+	tx   *sql.Tx
+	keys map[string]map[string]bool // written keys by table names
+}
+
+func (w *writer) write(ctx context.Context, resp models.Response, full bool) error {
+	for _, v := range resp.Instrument {
+		if _, err := w.upsert(ctx, instruments, []any{v.ID}, v.Title, v.ShortTitle, v.Symbol, v.Rate, v.Changed); err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.Company {
+		if _, err := w.upsert(ctx, companies, []any{v.ID}, v.Title, v.FullTitle, v.Www, v.Country, v.CountryCode,
+			v.Deleted, v.Changed); err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.User {
+		if _, err := w.upsert(ctx, users, []any{v.ID}, v.Login, v.Email, v.Parent, v.Country, v.CountryCode,
+			nonZero(v.Currency), v.PaidTill, v.MonthStartDay, v.IsForecastEnabled, v.PlanBalanceMode,
+			v.PlanSettings, v.Subscription, v.SubscriptionRenewalDate, v.Changed); err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.Account {
+		if _, err := w.upsert(ctx, accounts, []any{v.ID}, v.User, v.Instrument, v.Type, v.Role, v.Private, v.Savings,
+			v.Title, v.InBalance, v.CreditLimit, v.StartBalance, v.Balance, v.Company, v.Archive,
+			v.EnableCorrection, v.BalanceCorrectionType, v.StartDate, v.Capitalization, v.Percent,
+			jsonList(v.SyncID), v.EnableSMS, v.EndDateOffset, v.EndDateOffsetInterval, v.PayoffStep,
+			v.PayoffInterval, v.Changed); err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.Tag {
+		if _, err := w.upsert(ctx, tags, []any{v.ID}, v.User, v.Title, v.Parent, v.Icon, v.Picture, v.Color,
+			v.ShowIncome, v.ShowOutcome, v.BudgetIncome, v.BudgetOutcome, v.Required, v.StaticID,
+			v.Changed); err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.Merchant {
+		if _, err := w.upsert(ctx, merchants, []any{v.ID}, v.User, v.Title, v.Changed); err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.Budget {
+		tag := ""
+		if v.Tag != nil {
+			tag = *v.Tag
+		}
+		if _, err := w.upsert(ctx, budgets, []any{v.User, tag, v.Date}, v.Income, v.Outcome, v.IncomeLock,
+			v.OutcomeLock, v.IsIncomeForecast, v.IsOutcomeForecast, v.Changed); err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.Reminder {
+		ok, err := w.upsert(ctx, reminders, []any{v.ID}, v.User, v.Income, v.Outcome, v.IncomeInstrument,
+			v.OutcomeInstrument, v.IncomeAccount, v.OutcomeAccount, v.Step, jsonList(v.Points), v.StartDate,
+			v.EndDate, v.Interval, v.Notify, v.Comment, v.Payee, v.Merchant, v.Changed)
+		if err == nil && ok {
+			err = w.link(ctx, reminders, v.ID, v.Tag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.ReminderMarker {
+		ok, err := w.upsert(ctx, reminderMarkers, []any{v.ID}, v.User, v.Reminder, v.Date, v.Income, v.Outcome,
+			v.IncomeInstrument, v.OutcomeInstrument, v.IncomeAccount, v.OutcomeAccount, v.State, v.IsForecast,
+			v.Notify, v.Comment, v.Payee, v.Merchant, v.Changed)
+		if err == nil && ok {
+			err = w.link(ctx, reminderMarkers, v.ID, v.Tag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, v := range resp.Transaction {
+		ok, err := w.upsert(ctx, transactions, []any{v.ID}, v.User, v.Date, v.Income, v.Outcome, v.IncomeInstrument,
+			v.OutcomeInstrument, v.IncomeAccount, v.OutcomeAccount, v.OpIncome, v.OpOutcome,
+			v.OpIncomeInstrument, v.OpOutcomeInstrument, v.Payee, v.OriginalPayee, v.Merchant, v.Comment,
+			v.Deleted, v.Viewed, v.Hold, v.QRCode, v.Source, v.Latitude, v.Longitude, v.IncomeBankID,
+			v.OutcomeBankID, v.ReminderMarker, v.Created, v.Changed)
+		if err == nil && ok {
+			err = w.link(ctx, transactions, v.ID, v.Tag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, d := range resp.Deletion {
+		if err := w.delete(ctx, d); err != nil {
+			return err
+		}
+	}
+	if !full {
+		return nil
+	}
+	owners := make([]int, 0, len(resp.User))
+	for _, u := range resp.User {
+		owners = append(owners, u.ID)
+	}
+	// children first, so rows are deleted before the ones they refer to
+	for _, t := range slices.Backward(tables) {
+		if t.owned {
+			if err := w.prune(ctx, t, owners); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// upsert writes a row of t, false is returned if the row is kept as it's newer.
+func (w *writer) upsert(ctx context.Context, t table, key []any, values ...any) (bool, error) {
+	if w.keys[t.name] == nil {
+		w.keys[t.name] = map[string]bool{}
+	}
+	w.keys[t.name][keyString(key...)] = true
+	res, err := w.tx.ExecContext(ctx, t.upsert(), append(key, values...)...)
+	if err != nil {
+		return false, fmt.Errorf("can't write %s %v: %w", t.entity, key, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// link replaces links of entity of t to tags.
+func (w *writer) link(ctx context.Context, t table, id string, tagIDs []string) error {
+	// #nosec G201 - names of tables and columns are constants
+	if _, err := w.tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", t.links, t.linkKey), id); err != nil {
+		return err
+	}
+	// #nosec G201 - names of tables and columns are constants
+	q := fmt.Sprintf("INSERT OR IGNORE INTO %s (%s, tag_id, position) VALUES (?, ?, ?)", t.links, t.linkKey)
+	for i, tag := range tagIDs {
+		if _, err := w.tx.ExecContext(ctx, q, id, tag, i); err != nil {
+			return fmt.Errorf("can't write tags of %s %s: %w", t.entity, id, err)
+		}
+	}
+	return nil
+}
+
+// delete applies deletion of an entity and saves it.
+func (w *writer) delete(ctx context.Context, d models.Deletion) error {
+	for _, t := range tables {
+		if t.entity != d.Object || len(t.key) != 1 {
+			continue
+		}
+		if err := w.remove(ctx, t, []any{d.ID}); err != nil {
+			return err
+		}
+	}
+	_, err := w.tx.ExecContext(ctx, `INSERT INTO deletions (object, id, user, stamp) VALUES (?, ?, ?, ?)
+		ON CONFLICT (object, id) DO UPDATE SET user = excluded.user, stamp = excluded.stamp`, d.Object, d.ID, d.User, d.Stamp)
+	return err
+}
+
+// prune deletes rows of t owned by users which are not written by the transaction.
+func (w *writer) prune(ctx context.Context, t table, users []int) error {
+	if len(users) == 0 {
+		return nil
+	}
+	// #nosec G201 - names of tables and columns are constants
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE user IN (%s)", strings.Join(t.key, ", "), t.name,
+		strings.TrimSuffix(strings.Repeat("?, ", len(users)), ", "))
+	args := make([]any, 0, len(users))
+	for _, u := range users {
+		args = append(args, u)
+	}
+	rows, err := w.tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	var stale [][]any
+	for rows.Next() {
+		key := make([]any, len(t.key))
+		dest := make([]any, len(t.key))
+		for i := range key {
+			dest[i] = &key[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if !w.keys[t.name][keyString(key...)] {
+			stale = append(stale, key)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, key := range stale {
+		if err := w.remove(ctx, t, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove deletes a row of t with its links, the links go first.
+func (w *writer) remove(ctx context.Context, t table, key []any) error {
+	if t.links != "" {
+		// #nosec G201 - names of tables and columns are constants
+		if _, err := w.tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", t.links, t.linkKey), key[0]); err != nil {
+			return fmt.Errorf("can't delete tags of %s %v: %w", t.entity, key, err)
+		}
+	}
+	cond := make([]string, 0, len(t.key))
+	for _, c := range t.key {
+		cond = append(cond, c+" = ?")
+	}
+	// #nosec G201 - names of tables and columns are constants
+	if _, err := w.tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", t.name, strings.Join(cond, " AND ")), key...); err != nil {
+		return fmt.Errorf("can't delete %s %v: %w", t.entity, key, err)
+	}
+	return nil
+}
+
+// keyString makes comparable key of values of the primary key, the same for Go and database values.
+func keyString(values ...any) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		switch v := v.(type) {
+		case int:
+			parts = append(parts, strconv.Itoa(v))
+		case []byte:
+			parts = append(parts, string(v))
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// jsonList returns JSON array of values, nil if there are none.
+func jsonList[T any](values []T) any {
+	if len(values) == 0 {
+		return nil
+	}
+	bs, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+	return string(bs)
+}
+
+// nonZero returns nil for zero id, it's unknown.
+func nonZero(id int) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
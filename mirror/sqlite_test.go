@@ -0,0 +1,246 @@
+package mirror
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testResponse() models.Response {
+	rub := int32(1)
+	balance := 1500.5
+	comment := "lunch"
+	return models.Response{
+		ServerTimestamp: 1700000000,
+		Instrument:      []models.Instrument{{ID: 1, Title: "Russian Ruble", ShortTitle: "RUB", Symbol: "₽", Rate: 1, Changed: 100}},
+		Company:         []models.Company{{ID: 4, Title: "Bank", Changed: 100}},
+		User:            []models.User{{ID: 10, Login: "me", Currency: 1, Changed: 100}},
+		Account: []models.Account{
+			{ID: "card", User: 10, Instrument: &rub, Type: "ccard", Title: "Card", Balance: &balance, SyncID: []string{"1234"}, Changed: 100},
+			{ID: "cash", User: 10, Instrument: &rub, Type: "cash", Title: "Cash", Changed: 100},
+		},
+		Tag: []models.Tag{
+			{ID: "food", User: 10, Title: "Food", Changed: 100},
+			{ID: "work", User: 10, Title: "Work", Changed: 100},
+		},
+		Merchant: []models.Merchant{{ID: "cafe", User: 10, Title: "Cafe", Changed: 100}},
+		Budget:   []models.Budget{{User: 10, Date: "2024-03-01", Tag: strPtr("food"), Outcome: 300, Changed: 100}, {User: 10, Date: "2024-03-01", Outcome: 1000, Changed: 100}},
+		Reminder: []models.Reminder{{ID: "rent", User: 10, Outcome: 500, IncomeAccount: "card", OutcomeAccount: "card",
+			IncomeInstrument: 1, OutcomeInstrument: 1, Points: []int{1}, Tag: []string{"work"}, Changed: 100}},
+		ReminderMarker: []models.ReminderMarker{{
+			ID: "rent-march", User: 10, Reminder: "rent", Date: "2024-03-01", IncomeAccount: "card", OutcomeAccount: "card",
+			IncomeInstrument: 1, OutcomeInstrument: 1, State: "planned", Tag: []string{"work"}, Changed: 100,
+		}},
+		Transaction: []models.Transaction{
+			{ID: "tx1", User: 10, Date: "2024-03-12", Outcome: 250, IncomeAccount: "card", OutcomeAccount: strPtr("card"),
+				IncomeInstrument: 1, OutcomeInstrument: 1, Tag: []string{"food", "work"}, Merchant: strPtr("cafe"), Comment: &comment, Changed: 100},
+			{ID: "tx2", User: 10, Date: "2024-03-13", Income: 100, IncomeAccount: "cash", IncomeInstrument: 1, OutcomeInstrument: 1, Changed: 100},
+		},
+	}
+}
+
+func TestSQLite_Apply(t *testing.T) {
+	m := NewSQLite(filepath.Join(t.TempDir(), "zen.db"))
+	assert.NoError(t, m.Apply(context.Background(), testResponse(), true))
+	db := openDB(t, m.Path())
+
+	for name, want := range map[string]int{
+		"instruments": 1, "companies": 1, "users": 1, "accounts": 2, "tags": 2, "merchants": 1, "budgets": 2,
+		"reminders": 1, "reminder_markers": 1, "transactions": 2, "transaction_tags": 2, "reminder_tags": 1,
+		"reminder_marker_tags": 1, "deletions": 0,
+	} {
+		assert.Equal(t, want, count(t, db, "SELECT count(*) FROM "+name), name)
+	}
+
+	var title, comment, merchant string
+	var amount float64
+	assert.NoError(t, db.QueryRow(`SELECT t.title, tx.comment, m.title, tx.outcome FROM transactions tx
+		JOIN transaction_tags tt ON tt.transaction_id = tx.id AND tt.position = 0
+		JOIN tags t ON t.id = tt.tag_id
+		JOIN merchants m ON m.id = tx.merchant
+		WHERE tx.id = 'tx1'`).Scan(&title, &comment, &merchant, &amount))
+	assert.Equal(t, []any{"Food", "lunch", "Cafe", 250.0}, []any{title, comment, merchant, amount})
+
+	var syncID sql.NullString
+	var balance sql.NullFloat64
+	assert.NoError(t, db.QueryRow(`SELECT sync_id, balance FROM accounts WHERE id = 'card'`).Scan(&syncID, &balance))
+	assert.Equal(t, `["1234"]`, syncID.String)
+	assert.InDelta(t, 1500.5, balance.Float64, 1e-9)
+	assert.Equal(t, 0, count(t, db, `SELECT count(*) FROM accounts WHERE id = 'cash' AND sync_id IS NOT NULL`))
+	assert.Equal(t, 1, count(t, db, `SELECT count(*) FROM budgets WHERE tag = ''`))
+
+	// the same data again changes nothing
+	assert.NoError(t, m.Apply(context.Background(), testResponse(), true))
+	assert.Equal(t, 2, count(t, db, "SELECT count(*) FROM transactions"))
+	assert.Equal(t, 2, count(t, db, "SELECT count(*) FROM transaction_tags"))
+}
+
+func TestSQLite_ApplyDelta(t *testing.T) {
+	m := NewSQLite(filepath.Join(t.TempDir(), "zen.db"))
+	assert.NoError(t, m.Apply(context.Background(), testResponse(), true))
+	db := openDB(t, m.Path())
+
+	delta := models.Response{
+		Tag: []models.Tag{{ID: "food", User: 10, Title: "Old food", Changed: 50}}, // older than the mirror
+		Transaction: []models.Transaction{
+			{ID: "tx1", User: 10, Date: "2024-03-12", Outcome: 300, IncomeAccount: "card", Tag: []string{"work"}, Changed: 200},
+			{ID: "tx3", User: 10, Date: "2024-03-14", Outcome: 10, IncomeAccount: "cash", Changed: 200},
+		},
+		Deletion: []models.Deletion{
+			{ID: "tx2", Object: "transaction", Stamp: 200, User: 10},
+			{ID: "cafe", Object: "merchant", Stamp: 200, User: 10},
+		},
+	}
+	assert.NoError(t, m.Apply(context.Background(), delta, false))
+
+	var title string
+	assert.NoError(t, db.QueryRow(`SELECT title FROM tags WHERE id = 'food'`).Scan(&title))
+	assert.Equal(t, "Food", title, "newer row is kept")
+	var outcome float64
+	assert.NoError(t, db.QueryRow(`SELECT outcome FROM transactions WHERE id = 'tx1'`).Scan(&outcome))
+	assert.InDelta(t, 300.0, outcome, 1e-9)
+	assert.Equal(t, 1, count(t, db, `SELECT count(*) FROM transaction_tags WHERE transaction_id = 'tx1' AND tag_id = 'work' AND position = 0`))
+	assert.Equal(t, 1, count(t, db, `SELECT count(*) FROM transaction_tags`))
+	assert.Equal(t, 2, count(t, db, `SELECT count(*) FROM transactions`), "tx2 is deleted, tx3 is added")
+	assert.Equal(t, 0, count(t, db, `SELECT count(*) FROM merchants`))
+	assert.Equal(t, 2, count(t, db, `SELECT count(*) FROM deletions WHERE stamp = 200`))
+	assert.Equal(t, 2, count(t, db, `SELECT count(*) FROM accounts`), "untouched rows are kept")
+}
+
+func TestSQLite_ApplyFullPrunes(t *testing.T) {
+	m := NewSQLite(filepath.Join(t.TempDir(), "zen.db"))
+	assert.NoError(t, m.Apply(context.Background(), testResponse(), true))
+	other := models.Response{
+		User:        []models.User{{ID: 20, Login: "other", Changed: 100}},
+		Transaction: []models.Transaction{{ID: "other-tx", User: 20, Date: "2024-03-01", IncomeAccount: "x", Changed: 100}},
+	}
+	assert.NoError(t, m.Apply(context.Background(), other, true))
+
+	resp := testResponse()
+	resp.Transaction = resp.Transaction[1:]
+	resp.Budget = resp.Budget[:1]
+	assert.NoError(t, m.Apply(context.Background(), resp, true))
+
+	db := openDB(t, m.Path())
+	assert.Equal(t, 0, count(t, db, `SELECT count(*) FROM transactions WHERE id = 'tx1'`))
+	assert.Equal(t, 0, count(t, db, `SELECT count(*) FROM transaction_tags`))
+	assert.Equal(t, 1, count(t, db, `SELECT count(*) FROM budgets`))
+	assert.Equal(t, 1, count(t, db, `SELECT count(*) FROM transactions WHERE id = 'other-tx'`), "rows of other users are kept")
+}
+
+func TestSQLite_ForeignKeys(t *testing.T) {
+	m := NewSQLite(filepath.Join(t.TempDir(), "zen.db"))
+	assert.NoError(t, m.Apply(context.Background(), testResponse(), true))
+	db := openDB(t, m.dsn())
+
+	rows, err := db.Query(`PRAGMA foreign_key_check`)
+	assert.NoError(t, err)
+	assert.False(t, rows.Next(), "no violations")
+	assert.NoError(t, rows.Close())
+
+	_, err = db.Exec(`INSERT INTO transaction_tags (transaction_id, tag_id, position) VALUES ('gone', 'food', 0)`)
+	assert.ErrorContains(t, err, "FOREIGN KEY constraint failed")
+	_, err = db.Exec(`DELETE FROM transactions WHERE id = 'tx1'`)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count(t, db, `SELECT count(*) FROM transaction_tags WHERE transaction_id = 'tx1'`), "links are cascaded")
+}
+
+// TestSQLite_DanglingReferences shows why references between entities have no foreign keys: ZenMoney
+// data leaves them dangling, so mirror would reject it.
+func TestSQLite_DanglingReferences(t *testing.T) {
+	valid := testResponse()
+	valid.Budget = valid.Budget[:1]
+
+	tbl := []struct {
+		name string
+		full []bool
+		resp []models.Response
+		want []string // dangling references
+	}{
+		{name: "valid data", full: []bool{true}, resp: []models.Response{valid}},
+		{name: "budget of all categories", full: []bool{true}, resp: []models.Response{testResponse()},
+			want: []string{"budgets.tag"}},
+		{name: "account of other user", full: []bool{true}, resp: []models.Response{{
+			Instrument: valid.Instrument,
+			User:       []models.User{{ID: 20, Login: "other", Changed: 100}},
+			Transaction: []models.Transaction{{ID: "other-tx", User: 20, Date: "2024-03-01", IncomeAccount: "x",
+				IncomeInstrument: 1, OutcomeInstrument: 1, Changed: 100}},
+		}}, want: []string{"transactions.income_account"}},
+		{name: "deleted merchant", full: []bool{true, false}, resp: []models.Response{valid, {
+			Deletion: []models.Deletion{{ID: "cafe", Object: "merchant", Stamp: 200, User: 10}},
+		}}, want: []string{"transactions.merchant"}},
+		{name: "delta before full backup", full: []bool{false}, resp: []models.Response{{
+			Transaction: []models.Transaction{{ID: "tx3", User: 10, Date: "2024-03-14", IncomeAccount: "cash",
+				IncomeInstrument: 1, OutcomeInstrument: 1, Changed: 200}},
+		}}, want: []string{"transactions.user", "transactions.income_instrument", "transactions.outcome_instrument",
+			"transactions.income_account"}},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewSQLite(filepath.Join(t.TempDir(), "zen.db"))
+			for i, resp := range tt.resp {
+				assert.NoError(t, m.Apply(context.Background(), resp, tt.full[i]))
+			}
+			assert.Equal(t, tt.want, dangling(t, openDB(t, m.Path())))
+		})
+	}
+}
+
+// dangling returns references noted in schema, like "transactions.merchant", with values missing in
+// the referenced table.
+func dangling(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	tableRe := regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS (\w+) \(`)
+	refRe := regexp.MustCompile(`^\t(\w+) .*-- (\w+)\.id`)
+	var res []string
+	table := ""
+	for line := range strings.Lines(schema) {
+		if m := tableRe.FindStringSubmatch(line); m != nil {
+			table = m[1]
+			continue
+		}
+		m := refRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		q := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s IS NOT NULL AND %s NOT IN (SELECT id FROM %s)",
+			table, m[1], m[1], m[2])
+		if count(t, db, q) > 0 {
+			res = append(res, table+"."+m[1])
+		}
+	}
+	return res
+}
+
+func TestSQLite_ApplyError(t *testing.T) {
+	m := NewSQLite(filepath.Join(t.TempDir(), "missing", "zen.db"))
+	err := m.Apply(context.Background(), testResponse(), true)
+	assert.ErrorContains(t, err, "can't create schema of")
+}
+
+func openDB(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func count(t *testing.T, db *sql.DB, q string) int {
+	t.Helper()
+	var n int
+	assert.NoError(t, db.QueryRow(q).Scan(&n), q)
+	return n
+}
+
+func strPtr(s string) *string {
+	return &s
+}
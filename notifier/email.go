@@ -0,0 +1,186 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Security modes of SMTP connection.
+const (
+	SMTPStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 587
+	SMTPTLS      = "tls"      // implicit TLS, usually port 465
+	SMTPNone     = "none"     // no encryption, for local relays only
+)
+
+// EmailOpts are settings of Email notifier.
+type EmailOpts struct {
+	Host     string
+	Port     int
+	Security string // SMTPStartTLS if empty
+	Username string // authentication is skipped if empty
+	Password string
+	From     string
+	To       []string
+}
+
+// Email is a notifier that sends emails via SMTP.
+type Email struct {
+	opts      EmailOpts
+	tlsConfig *tls.Config
+	timeout   time.Duration
+}
+
+// NewEmail creates a new Email notifier.
+func NewEmail(opts EmailOpts) *Email {
+	if opts.Security == "" {
+		opts.Security = SMTPStartTLS
+	}
+	return &Email{
+		opts:      opts,
+		tlsConfig: &tls.Config{ServerName: opts.Host, MinVersion: tls.VersionTLS12},
+		timeout:   time.Minute,
+	}
+}
+
+// Notify sends an email with the notification.
+func (e *Email) Notify(title, message string) error {
+	body := "<h3>" + html.EscapeString(title) + "</h3>\n<pre>" + html.EscapeString(message) + "</pre>\n"
+	return e.Send(title, message, body)
+}
+
+// Send sends an email with plain text and HTML versions of the body to all recipients.
+func (e *Email) Send(subject, text, htmlBody string) error {
+	if len(e.opts.To) == 0 {
+		return errors.New("no email recipients")
+	}
+	msg, err := e.message(subject, text, htmlBody)
+	if err != nil {
+		return err
+	}
+
+	c, err := e.dial()
+	if err != nil {
+		return fmt.Errorf("can't connect to smtp server: %w", err)
+	}
+	defer func() {
+		_ = c.Close()
+	}()
+
+	if e.opts.Security == SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server doesn't support STARTTLS")
+		}
+		if err := c.StartTLS(e.tlsConfig); err != nil {
+			return fmt.Errorf("starttls failed: %w", err)
+		}
+	}
+	if e.opts.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.opts.Username, e.opts.Password, e.opts.Host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+	if err := c.Mail(e.opts.From); err != nil {
+		return err
+	}
+	for _, to := range e.opts.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s is rejected: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (e *Email) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(e.opts.Host, strconv.Itoa(e.opts.Port))
+	dialer := &net.Dialer{Timeout: e.timeout}
+
+	var conn net.Conn
+	var err error
+	switch e.opts.Security {
+	case SMTPTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, e.tlsConfig)
+	case SMTPStartTLS, SMTPNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown smtp security %q", e.opts.Security)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(e.timeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, e.opts.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// message makes multipart/alternative message with plain text and HTML bodies.
+func (e *Email) message(subject, text, htmlBody string) ([]byte, error) {
+	body := bytes.Buffer{}
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlBody},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	res := bytes.Buffer{}
+	for _, h := range [][2]string{
+		{"From", e.opts.From},
+		{"To", strings.Join(e.opts.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", `multipart/alternative; boundary="` + mw.Boundary() + `"`},
+	} {
+		res.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	res.WriteString("\r\n")
+	res.Write(body.Bytes())
+	return res.Bytes(), nil
+}
@@ -0,0 +1,279 @@
+package notifier
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTP is an in-process SMTP server accepting all mail.
+type fakeSMTP struct {
+	ln       net.Listener
+	tls      *tls.Config // STARTTLS is offered if set
+	implicit bool        // connections are TLS from the start
+
+	mu   sync.Mutex
+	mail []fakeMail
+}
+
+type fakeMail struct {
+	auth string // decoded AUTH PLAIN credentials
+	tls  bool
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T, cfg *tls.Config, implicit bool) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if implicit {
+		ln = tls.NewListener(ln, cfg)
+	}
+	s := &fakeSMTP{ln: ln, tls: cfg, implicit: implicit}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) port() int {
+	return tcpPort(s.ln.Addr())
+}
+
+func tcpPort(addr net.Addr) int {
+	if a, ok := addr.(*net.TCPAddr); ok {
+		return a.Port
+	}
+	return 0
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	m := fakeMail{tls: s.implicit}
+	_ = tc.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			ext := []string{"250-fake"}
+			if s.tls != nil && !m.tls {
+				ext = append(ext, "250-STARTTLS")
+			}
+			_ = tc.PrintfLine("%s\r\n250 AUTH PLAIN", strings.Join(ext, "\r\n"))
+		case "STARTTLS":
+			_ = tc.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tc, m.tls = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			m.auth = string(creds)
+			_ = tc.PrintfLine("235 authenticated")
+		case "MAIL":
+			m.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			_ = tc.PrintfLine("250 ok")
+		case "RCPT":
+			to := strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if strings.HasPrefix(to, "rejected") {
+				_ = tc.PrintfLine("550 no such user")
+				continue
+			}
+			m.to = append(m.to, to)
+			_ = tc.PrintfLine("250 ok")
+		case "DATA":
+			_ = tc.PrintfLine("354 go ahead")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.data = string(data)
+			s.mu.Lock()
+			s.mail = append(s.mail, m)
+			s.mu.Unlock()
+			_ = tc.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tc.PrintfLine("221 bye")
+			return
+		default:
+			_ = tc.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+func (s *fakeSMTP) received() []fakeMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mail
+}
+
+// testTLS returns server config with self-signed certificate for 127.0.0.1 and client config trusting it.
+func testTLS(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}, MinVersion: tls.VersionTLS12}
+	client = &tls.Config{ServerName: "127.0.0.1", RootCAs: pool, MinVersion: tls.VersionTLS12}
+	return server, client
+}
+
+// parseMail returns subject and bodies of the message by content type.
+func parseMail(t *testing.T, data string) (subject string, parts map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if !assert.NoError(t, err) {
+		return "", nil
+	}
+	subject, err = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	parts = map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			assert.ErrorIs(t, err, io.EOF)
+			return subject, parts
+		}
+		bs, err := io.ReadAll(p) // quoted-printable is decoded by the reader
+		assert.NoError(t, err)
+		parts[p.Header.Get("Content-Type")] = string(bs)
+	}
+}
+
+func TestEmail_Notify(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+
+	tbl := []struct {
+		name     string
+		security string
+		implicit bool
+	}{
+		{"starttls", SMTPStartTLS, false},
+		{"implicit tls", SMTPTLS, true},
+		{"no tls", SMTPNone, false},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeSMTP(t, serverTLS, tt.implicit)
+			e := NewEmail(EmailOpts{Host: "127.0.0.1", Port: srv.port(), Security: tt.security,
+				Username: "user", Password: "pass", From: "zenb@example.com", To: []string{"one@example.com", "two@example.com"}})
+			e.tlsConfig = clientTLS
+
+			assert.NoError(t, e.Notify("home: Backup Export Error", "INVALID_TOKEN: <token> is not provided"))
+			received := srv.received()
+			if !assert.Len(t, received, 1) {
+				return
+			}
+			m := received[0]
+			assert.Equal(t, tt.security != SMTPNone, m.tls)
+			assert.Equal(t, "\x00user\x00pass", m.auth)
+			assert.Equal(t, "zenb@example.com", m.from)
+			assert.Equal(t, []string{"one@example.com", "two@example.com"}, m.to)
+
+			subject, parts := parseMail(t, m.data)
+			assert.Equal(t, "home: Backup Export Error", subject)
+			assert.Equal(t, "INVALID_TOKEN: <token> is not provided", parts["text/plain; charset=utf-8"])
+			assert.Equal(t, "<h3>home: Backup Export Error</h3>\n<pre>INVALID_TOKEN: &lt;token&gt; is not provided</pre>\n",
+				parts["text/html; charset=utf-8"])
+		})
+	}
+}
+
+func TestEmail_SendErrors(t *testing.T) {
+	serverTLS, clientTLS := testTLS(t)
+
+	srv := newFakeSMTP(t, nil, false)
+	e := NewEmail(EmailOpts{Host: "127.0.0.1", Port: srv.port(), From: "zenb@example.com", To: []string{"one@example.com"}})
+	assert.EqualError(t, e.Send("subject", "text", "html"), "smtp server doesn't support STARTTLS")
+
+	srv = newFakeSMTP(t, serverTLS, false)
+	e = NewEmail(EmailOpts{Host: "127.0.0.1", Port: srv.port(), From: "zenb@example.com", To: []string{"one@example.com"}})
+	assert.ErrorContains(t, e.Send("subject", "text", "html"), "starttls failed", "certificate is not trusted")
+
+	e = NewEmail(EmailOpts{Host: "127.0.0.1", Port: srv.port(), From: "zenb@example.com", To: []string{"one@example.com", "rejected@example.com"}})
+	e.tlsConfig = clientTLS
+	assert.ErrorContains(t, e.Send("subject", "text", "html"), "recipient rejected@example.com is rejected: 550")
+	assert.Empty(t, srv.received())
+
+	e = NewEmail(EmailOpts{Host: "127.0.0.1", Port: srv.port(), Security: "ssl", To: []string{"one@example.com"}})
+	assert.ErrorContains(t, e.Send("subject", "text", "html"), `unknown smtp security "ssl"`)
+
+	e = NewEmail(EmailOpts{Host: "127.0.0.1", Port: srv.port()})
+	assert.EqualError(t, e.Send("subject", "text", "html"), "no email recipients")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := tcpPort(ln.Addr())
+	assert.NoError(t, ln.Close())
+	e = NewEmail(EmailOpts{Host: "127.0.0.1", Port: port, To: []string{"one@example.com"}})
+	assert.ErrorContains(t, e.Send("subject", "text", "html"), "can't connect to smtp server")
+}
+
+func TestEmail_message(t *testing.T) {
+	e := NewEmail(EmailOpts{From: "zenb@example.com", To: []string{"one@example.com", "two@example.com"}})
+	bs, err := e.message("Отчёт\r\nBcc: evil@example.com", "text", "<p>html</p>")
+	assert.NoError(t, err)
+
+	r := bufio.NewReader(strings.NewReader(string(bs)))
+	h, err := textproto.NewReader(r).ReadMIMEHeader()
+	assert.NoError(t, err)
+	assert.Equal(t, "zenb@example.com", h.Get("From"))
+	assert.Equal(t, "one@example.com, two@example.com", h.Get("To"))
+	assert.Empty(t, h.Get("Bcc"), "headers can't be injected with subject")
+	assert.Equal(t, "1.0", h.Get("MIME-Version"))
+
+	subject, parts := parseMail(t, string(bs))
+	assert.Equal(t, "Отчёт\r\nBcc: evil@example.com", subject)
+	assert.Equal(t, map[string]string{"text/plain; charset=utf-8": "text", "text/html; charset=utf-8": "<p>html</p>"}, parts)
+}
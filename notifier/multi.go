@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"errors"
+)
+
+// Multi is a notifier that sends notifications to all its notifiers.
+type Multi []Notifier
+
+// Notify sends the notification to every notifier, failure of one doesn't stop others.
+func (m Multi) Notify(title, message string) error {
+	errs := make([]error, 0, len(m))
+	for _, n := range m {
+		errs = append(errs, n.Notify(title, message))
+	}
+	return errors.Join(errs...)
+}
+
+// NotifyResult sends the result to every notifier supporting results, see ResultNotifier.
+func (m Multi) NotifyResult(r Result) error {
+	errs := make([]error, 0, len(m))
+	for _, n := range m {
+		if rn, ok := n.(ResultNotifier); ok {
+			errs = append(errs, rn.NotifyResult(r))
+		}
+	}
+	return errors.Join(errs...)
+}
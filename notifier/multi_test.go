@@ -0,0 +1,46 @@
+package notifier
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type notifierFunc func(title, message string) error
+
+func (f notifierFunc) Notify(title, message string) error {
+	return f(title, message)
+}
+
+func TestMulti_Notify(t *testing.T) {
+	var calls []string
+	ok := notifierFunc(func(title, message string) error {
+		calls = append(calls, title+": "+message)
+		return nil
+	})
+	failing := notifierFunc(func(_, _ string) error {
+		return errors.New("failed")
+	})
+
+	assert.NoError(t, Multi{}.Notify("title", "message"))
+	assert.EqualError(t, Multi{failing, ok}.Notify("title", "message"), "failed")
+	assert.Equal(t, []string{"title: message"}, calls, "other notifiers are called")
+}
+
+type resultNotifier struct {
+	notifierFunc
+	results []Result
+}
+
+func (n *resultNotifier) NotifyResult(r Result) error {
+	n.results = append(n.results, r)
+	return nil
+}
+
+func TestMulti_NotifyResult(t *testing.T) {
+	rn := &resultNotifier{}
+	m := Multi{NewNoop(), rn}
+	assert.NoError(t, m.NotifyResult(Result{File: "zen_2024-06-29_15-30-00.json"}))
+	assert.Equal(t, []Result{{File: "zen_2024-06-29_15-30-00.json"}}, rn.results)
+}
@@ -14,6 +14,23 @@ type Notifier interface {
 	Notify(title, message string) error
 }
 
+// ResultNotifier is an optional Notifier extension, it gets the outcome of every successful run,
+// including skipped duplicates.
+type ResultNotifier interface {
+	NotifyResult(r Result) error
+}
+
+// Result is the outcome of a backup run.
+type Result struct {
+	Profile string
+	Time    time.Time
+	File    string // saved file, or the previous one if the backup is skipped
+	Size    int
+	Hash    string // sha256 of normalized data, see snapshot.Hash
+	Delta   bool
+	Skipped bool // nothing changed since the previous backup
+}
+
 // Noop is a no-op notifier that does nothing.
 type Noop struct{}
 
@@ -0,0 +1,165 @@
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sender sends messages with plain text and HTML bodies, e.g. Email.
+type Sender interface {
+	Send(subject, text, html string) error
+}
+
+// Report collects backup runs and sends a report of them every period, e.g. weekly. It's sent
+// with the first run after the period is over, runs are kept in memory and lost on restart.
+type Report struct {
+	sender Sender
+	period time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	since time.Time
+	runs  []reportRun
+}
+
+type reportRun struct {
+	Result
+	Error string // title and message of failure notification, empty if the run succeeded
+}
+
+// NewReport creates a new Report sending reports by sender every period.
+func NewReport(sender Sender, period time.Duration) *Report {
+	return &Report{sender: sender, period: period, now: time.Now, since: time.Now()}
+}
+
+// Notify records a failed run.
+func (r *Report) Notify(title, message string) error {
+	return r.add(reportRun{Result: Result{Time: r.now()}, Error: title + ": " + message})
+}
+
+// NotifyResult records a successful run.
+func (r *Report) NotifyResult(res Result) error {
+	return r.add(reportRun{Result: res})
+}
+
+func (r *Report) add(run reportRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, run)
+
+	now := r.now()
+	if now.Sub(r.since) < r.period {
+		return nil
+	}
+	subject, text, body, err := r.render(now)
+	if err != nil {
+		return err
+	}
+	if err := r.sender.Send(subject, text, body); err != nil {
+		return fmt.Errorf("can't send report: %w", err)
+	}
+	r.since, r.runs = now, nil
+	return nil
+}
+
+// render returns subject, plain text and HTML body of the report.
+func (r *Report) render(now time.Time) (subject, text, body string, err error) {
+	data := reportData{From: r.since, To: now, Runs: r.runs}
+	for _, run := range r.runs {
+		switch {
+		case run.Error != "":
+			data.Failed++
+		case run.Skipped:
+			data.Skipped++
+		default:
+			data.Saved++
+			data.Size += run.Size
+		}
+	}
+
+	subject = fmt.Sprintf("Backup report %s - %s", data.From.Format(time.DateOnly), data.To.Format(time.DateOnly))
+	buf := bytes.Buffer{}
+	if err := reportTmpl.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+
+	lines := []string{
+		subject,
+		fmt.Sprintf("Saved: %d (%s), skipped: %d, failed: %d", data.Saved, formatSize(data.Size), data.Skipped, data.Failed),
+		"",
+	}
+	for _, run := range r.runs {
+		lines = append(lines, run.Time.Format(time.DateTime)+" "+run.status())
+	}
+	return subject, strings.Join(lines, "\n") + "\n", buf.String(), nil
+}
+
+// status returns human-readable outcome of the run.
+func (r reportRun) status() string {
+	var res string
+	switch {
+	case r.Error != "":
+		res = "failed: " + r.Error
+	case r.Skipped:
+		res = "skipped, unchanged since " + r.File
+	default:
+		res = fmt.Sprintf("saved %s (%s)", r.File, formatSize(r.Size))
+	}
+	if r.Profile != "" {
+		res = r.Profile + ": " + res
+	}
+	return res
+}
+
+type reportData struct {
+	From, To               time.Time
+	Runs                   []reportRun
+	Saved, Skipped, Failed int
+	Size                   int
+}
+
+func formatSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+var reportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string { return t.Format(time.DateTime) },
+	"date":     func(t time.Time) string { return t.Format(time.DateOnly) },
+	"size":     formatSize,
+}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>Backup report {{date .From}} - {{date .To}}</h2>
+<p>Saved: <b>{{.Saved}}</b> ({{size .Size}}), skipped: <b>{{.Skipped}}</b>, failed: <b{{if .Failed}} style="color: #c00"{{end}}>{{.Failed}}</b></p>
+<table cellpadding="4" style="border-collapse: collapse">
+<tr><th align="left">Time</th><th align="left">Profile</th><th align="left">Status</th><th align="left">File</th><th align="right">Size</th></tr>
+{{- range .Runs}}
+<tr>
+<td>{{datetime .Time}}</td>
+<td>{{.Profile}}</td>
+{{- if .Error}}
+<td style="color: #c00">failed</td><td colspan="2">{{.Error}}</td>
+{{- else if .Skipped}}
+<td>skipped</td><td>{{.File}}</td><td></td>
+{{- else}}
+<td style="color: #080">saved</td><td>{{.File}}</td><td align="right">{{size .Size}}</td>
+{{- end}}
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,94 @@
+package notifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sentReport struct {
+	subject, text, html string
+}
+
+type senderMock struct {
+	sent []sentReport
+	err  error
+}
+
+func (s *senderMock) Send(subject, text, html string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, sentReport{subject, text, html})
+	return nil
+}
+
+func TestReport(t *testing.T) {
+	start := time.Date(2024, 3, 4, 3, 0, 0, 0, time.UTC)
+	now := start
+	sender := &senderMock{}
+	r := NewReport(sender, 7*24*time.Hour)
+	r.now, r.since = func() time.Time { return now }, start
+
+	now = start.Add(time.Hour)
+	assert.NoError(t, r.NotifyResult(Result{Profile: "home", Time: now, File: "zen_2024-03-04_03-00-00.json", Size: 2048}))
+	now = start.Add(25 * time.Hour)
+	assert.NoError(t, r.NotifyResult(Result{Profile: "home", Time: now, File: "zen_2024-03-04_03-00-00.json", Skipped: true}))
+	now = start.Add(49 * time.Hour)
+	assert.NoError(t, r.Notify("home: Backup Export Error", "<timeout>"))
+	assert.Empty(t, sender.sent, "report is sent when the period is over")
+
+	now = start.Add(7 * 24 * time.Hour)
+	assert.NoError(t, r.NotifyResult(Result{Time: now, File: "zen_2024-03-11_03-00-00.json", Size: 3 << 20}))
+	if !assert.Len(t, sender.sent, 1) {
+		return
+	}
+	rep := sender.sent[0]
+	assert.Equal(t, "Backup report 2024-03-04 - 2024-03-11", rep.subject)
+	assert.Equal(t, `Backup report 2024-03-04 - 2024-03-11
+Saved: 2 (3.0 MiB), skipped: 1, failed: 1
+
+2024-03-04 04:00:00 home: saved zen_2024-03-04_03-00-00.json (2.0 KiB)
+2024-03-05 04:00:00 home: skipped, unchanged since zen_2024-03-04_03-00-00.json
+2024-03-06 04:00:00 failed: home: Backup Export Error: <timeout>
+2024-03-11 03:00:00 saved zen_2024-03-11_03-00-00.json (3.0 MiB)
+`, rep.text)
+	for _, s := range []string{
+		"<h2>Backup report 2024-03-04 - 2024-03-11</h2>",
+		`failed: <b style="color: #c00">1</b>`,
+		"<td>2024-03-04 04:00:00</td>\n<td>home</td>\n" +
+			`<td style="color: #080">saved</td><td>zen_2024-03-04_03-00-00.json</td><td align="right">2.0 KiB</td>`,
+		"<td>skipped</td><td>zen_2024-03-04_03-00-00.json</td>",
+		`<td colspan="2">home: Backup Export Error: &lt;timeout&gt;</td>`,
+	} {
+		assert.Contains(t, rep.html, s)
+	}
+
+	now = start.Add(8 * 24 * time.Hour)
+	assert.NoError(t, r.NotifyResult(Result{Time: now}))
+	assert.Len(t, sender.sent, 1, "runs of the next period are collected")
+	assert.Len(t, r.runs, 1)
+}
+
+func TestReport_sendError(t *testing.T) {
+	sender := &senderMock{err: errors.New("smtp is down")}
+	r := NewReport(sender, 0)
+	assert.EqualError(t, r.NotifyResult(Result{Time: time.Now()}), "can't send report: smtp is down")
+	assert.Len(t, r.runs, 1, "runs are kept until the report is sent")
+
+	sender.err = nil
+	assert.NoError(t, r.NotifyResult(Result{Time: time.Now()}))
+	if assert.Len(t, sender.sent, 1) {
+		assert.Contains(t, sender.sent[0].text, "Saved: 2 (0 B), skipped: 0, failed: 0")
+	}
+	assert.Empty(t, r.runs)
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0 B", formatSize(0))
+	assert.Equal(t, "1023 B", formatSize(1023))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "1.0 GiB", formatSize(1<<30))
+}
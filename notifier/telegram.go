@@ -0,0 +1,112 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const telegramAPIURL = "https://api.telegram.org"
+
+// TelegramOpts are settings of Telegram notifier.
+type TelegramOpts struct {
+	Token    string // bot token from @BotFather
+	ChatID   string // chat ID, e.g. -1001234567890, or @channel_name
+	ThreadID int    // optional topic of a forum chat
+	Silent   bool   // send without sound
+	APIURL   string // Bot API base URL, https://api.telegram.org if empty
+}
+
+// Telegram is a notifier that sends messages to a Telegram chat via Bot API.
+type Telegram struct {
+	opts TelegramOpts
+}
+
+// NewTelegram creates a new Telegram notifier.
+func NewTelegram(opts TelegramOpts) *Telegram {
+	if opts.APIURL == "" {
+		opts.APIURL = telegramAPIURL
+	}
+	opts.APIURL = strings.TrimSuffix(opts.APIURL, "/")
+	return &Telegram{opts: opts}
+}
+
+type telegramMessage struct {
+	ChatID              string `json:"chat_id"`
+	ThreadID            int    `json:"message_thread_id,omitempty"`
+	Text                string `json:"text"`
+	ParseMode           string `json:"parse_mode"`
+	DisableNotification bool   `json:"disable_notification,omitempty"`
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// Notify sends a message with bold title to the chat.
+func (t *Telegram) Notify(title, message string) error {
+	bs, err := json.Marshal(telegramMessage{
+		ChatID:              t.opts.ChatID,
+		ThreadID:            t.opts.ThreadID,
+		Text:                "*" + escapeMarkdown(title) + "*\n" + escapeMarkdown(message),
+		ParseMode:           "MarkdownV2",
+		DisableNotification: t.opts.Silent,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	endpoint := t.opts.APIURL + "/bot" + t.opts.Token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(bs))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram request failed: %w", redactURLError(err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var res telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || !res.OK {
+		if res.Description == "" {
+			res.Description = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("telegram responded with status %d: %s", resp.StatusCode, res.Description)
+	}
+	return nil
+}
+
+// escapeMarkdown escapes text for MarkdownV2 parse mode, where all the special characters must be escaped.
+func escapeMarkdown(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("_*[]()~`>#+-=|{}.!\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// redactURLError drops request URL from err, it contains the bot token.
+func redactURLError(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return fmt.Errorf("%s: %w", ue.Op, ue.Err)
+	}
+	return err
+}
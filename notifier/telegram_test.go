@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTelegram(t *testing.T) {
+	n := NewTelegram(TelegramOpts{Token: "123:abc", ChatID: "42"})
+	assert.Equal(t, "https://api.telegram.org", n.opts.APIURL)
+
+	n = NewTelegram(TelegramOpts{APIURL: "http://localhost:8081/"})
+	assert.Equal(t, "http://localhost:8081", n.opts.APIURL)
+}
+
+func TestTelegram_Notify(t *testing.T) {
+	var got map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		if r.URL.Path != "/bot123:abc/sendMessage" {
+			http.NotFound(w, r)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		got = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got["chat_id"] == "-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1}}`))
+	}))
+	defer ts.Close()
+
+	n := NewTelegram(TelegramOpts{Token: "123:abc", ChatID: "-1001234567890", ThreadID: 7, Silent: true, APIURL: ts.URL})
+	assert.NoError(t, n.Notify("Backup Export Error", "INVALID_TOKEN: token is not provided (status: 401)."))
+	assert.Equal(t, map[string]any{
+		"chat_id":              "-1001234567890",
+		"message_thread_id":    float64(7),
+		"text":                 "*Backup Export Error*\nINVALID\\_TOKEN: token is not provided \\(status: 401\\)\\.",
+		"parse_mode":           "MarkdownV2",
+		"disable_notification": true,
+	}, got)
+
+	n = NewTelegram(TelegramOpts{Token: "123:abc", ChatID: "@zenb", APIURL: ts.URL})
+	assert.NoError(t, n.Notify("title", "message"))
+	assert.NotContains(t, got, "message_thread_id")
+	assert.NotContains(t, got, "disable_notification")
+
+	n = NewTelegram(TelegramOpts{Token: "123:abc", ChatID: "-1", APIURL: ts.URL})
+	assert.EqualError(t, n.Notify("title", "message"), "telegram responded with status 400: Bad Request: chat not found")
+
+	n = NewTelegram(TelegramOpts{Token: "123:abc", ChatID: "42", APIURL: ts.URL + "/missing"})
+	err := n.Notify("title", "message")
+	assert.EqualError(t, err, "telegram responded with status 404: Not Found")
+
+	n = NewTelegram(TelegramOpts{Token: "123:abc", ChatID: "42", APIURL: "http://127.0.0.1:1"})
+	err = n.Notify("title", "message")
+	assert.ErrorContains(t, err, "telegram request failed")
+	assert.NotContains(t, err.Error(), "123:abc", "bot token is not leaked")
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	assert.Equal(t, `zen\_2024\-06\-29\.json \*saved\* \[1\]\(2\) \~\>\#\+\=\|\{\}\!\\`,
+		escapeMarkdown(`zen_2024-06-29.json *saved* [1](2) ~>#+=|{}!\`))
+}
@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// WebhookTemplate is the default body of Webhook, a JSON object with title and message.
+const WebhookTemplate = `{"title": {{json .Title}}, "message": {{json .Message}}}`
+
+// WebhookOpts are settings of Webhook notifier.
+type WebhookOpts struct {
+	URL         string
+	Method      string            // POST if empty
+	Headers     map[string]string // Content-Type is application/json if not set
+	Template    string            // text/template of the body, WebhookTemplate if empty
+	StatusCodes []int             // expected response status codes, any 2xx if empty
+}
+
+// Webhook is a notifier that sends a templated request to any HTTP endpoint, e.g. Slack, Discord or
+// Gotify. The template gets .Title and .Message, json function quotes a value as JSON string.
+type Webhook struct {
+	opts WebhookOpts
+	tmpl *template.Template
+}
+
+// NewWebhook creates a new Webhook notifier, it fails if the template can't be parsed.
+func NewWebhook(opts WebhookOpts) (*Webhook, error) {
+	if opts.Method == "" {
+		opts.Method = http.MethodPost
+	}
+	if opts.Template == "" {
+		opts.Template = WebhookTemplate
+	}
+	tmpl, err := template.New("webhook").Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			bs, err := json.Marshal(v)
+			return string(bs), err
+		},
+	}).Parse(opts.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	return &Webhook{opts: opts, tmpl: tmpl}, nil
+}
+
+// Notify sends the request with body made from the template.
+func (w *Webhook) Notify(title, message string) error {
+	body := bytes.Buffer{}
+	data := struct{ Title, Message string }{title, message}
+	if err := w.tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("can't render webhook template: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, w.opts.Method, w.opts.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", redactURLError(err))
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if w.expected(resp.StatusCode) {
+		// Discard response body to ensure connection reuse
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	bs, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	msg := strings.TrimSpace(string(bs))
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	return fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, msg)
+}
+
+func (w *Webhook) expected(code int) bool {
+	if len(w.opts.StatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+	return slices.Contains(w.opts.StatusCodes, code)
+}
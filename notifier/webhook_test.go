@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	var method, body string
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		method, body, header = r.Method, string(bs), r.Header
+		switch r.URL.Path {
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		case "/fail":
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+		case "/empty":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+
+	n, err := NewWebhook(WebhookOpts{URL: ts.URL})
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify("Backup Export Error", `"quoted" message`+"\nline"))
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.JSONEq(t, `{"title": "Backup Export Error", "message": "\"quoted\" message\nline"}`, body)
+
+	n, err = NewWebhook(WebhookOpts{
+		URL:      ts.URL + "/created",
+		Method:   http.MethodPut,
+		Headers:  map[string]string{"Authorization": "Bearer secret", "Content-Type": "text/plain"},
+		Template: "{{.Title}}: {{.Message}}",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify("title", "message"))
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "Bearer secret", header.Get("Authorization"))
+	assert.Equal(t, "text/plain", header.Get("Content-Type"))
+	assert.Equal(t, "title: message", body)
+
+	n, err = NewWebhook(WebhookOpts{URL: ts.URL + "/created", StatusCodes: []int{http.StatusOK}})
+	assert.NoError(t, err)
+	assert.EqualError(t, n.Notify("title", "message"), "webhook responded with status 201: Created")
+
+	n, err = NewWebhook(WebhookOpts{URL: ts.URL + "/fail"})
+	assert.NoError(t, err)
+	assert.EqualError(t, n.Notify("title", "message"), "webhook responded with status 400: invalid payload")
+
+	n, err = NewWebhook(WebhookOpts{URL: ts.URL + "/empty"})
+	assert.NoError(t, err)
+	assert.EqualError(t, n.Notify("title", "message"), "webhook responded with status 502: Bad Gateway")
+
+	n, err = NewWebhook(WebhookOpts{URL: ts.URL, Template: "{{.Unknown}}"})
+	assert.NoError(t, err)
+	assert.ErrorContains(t, n.Notify("title", "message"), "can't render webhook template")
+
+	n, err = NewWebhook(WebhookOpts{URL: "http://127.0.0.1:0/secret_token"})
+	assert.NoError(t, err)
+	err = n.Notify("title", "message")
+	assert.ErrorContains(t, err, "webhook request failed")
+	assert.NotContains(t, err.Error(), "secret_token")
+}
+
+func TestNewWebhook(t *testing.T) {
+	n, err := NewWebhook(WebhookOpts{URL: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, n.opts.Method)
+	assert.Equal(t, WebhookTemplate, n.opts.Template)
+
+	_, err = NewWebhook(WebhookOpts{Template: "{{.Title"})
+	assert.ErrorContains(t, err, "invalid webhook template")
+}
@@ -0,0 +1,112 @@
+// Package retention decides which backups to prune according to grandfather-father-son rotation rules.
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/snapshot"
+)
+
+// Policy defines which backups are kept. Every backup, full or delta, is a restore point.
+// A point is kept if any rule matches it, the most recent point is always kept. Keeping
+// a delta keeps the full backup and all deltas it depends on.
+type Policy struct {
+	KeepLast    int  // keep N most recent backups
+	KeepDaily   int  // keep the last backup of every day for D days
+	KeepWeekly  int  // keep the last backup of every week for W weeks
+	KeepMonthly int  // keep the last backup of every month for M months
+	KeepYearly  bool // keep the last backup of every year forever
+}
+
+// Enabled returns true if any rule is set, backups are never pruned otherwise.
+func (p Policy) Enabled() bool {
+	return p.KeepLast > 0 || p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.KeepYearly
+}
+
+// String returns human-readable policy description.
+func (p Policy) String() string {
+	return fmt.Sprintf("last=%d, daily=%d, weekly=%d, monthly=%d, yearly=%t",
+		p.KeepLast, p.KeepDaily, p.KeepWeekly, p.KeepMonthly, p.KeepYearly)
+}
+
+// Plan returns names of backups which should be deleted, oldest first. Files which are not
+// backups (e.g. the state file) are never deleted.
+func (p Policy) Plan(names []string, now time.Time) []string {
+	if !p.Enabled() {
+		return nil
+	}
+
+	files := make([]snapshot.File, 0, len(names))
+	for _, name := range names {
+		if f, ok := snapshot.ParseFileName(name); ok {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Time.Before(files[j].Time) })
+
+	keep := make([]bool, len(files))
+	keep[len(files)-1] = true
+	for i := len(files) - 1; i >= 0 && i >= len(files)-p.KeepLast; i-- {
+		keep[i] = true
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if p.KeepDaily > 0 {
+		keepPeriods(files, keep, today.AddDate(0, 0, -(p.KeepDaily-1)), func(t time.Time) string {
+			return t.Format("2006-01-02")
+		})
+	}
+	if p.KeepWeekly > 0 {
+		weekStart := today.AddDate(0, 0, -(int(today.Weekday())+6)%7) // monday
+		keepPeriods(files, keep, weekStart.AddDate(0, 0, -7*(p.KeepWeekly-1)), func(t time.Time) string {
+			y, w := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", y, w)
+		})
+	}
+	if p.KeepMonthly > 0 {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		keepPeriods(files, keep, monthStart.AddDate(0, -(p.KeepMonthly-1), 0), func(t time.Time) string {
+			return t.Format("2006-01")
+		})
+	}
+	if p.KeepYearly {
+		keepPeriods(files, keep, time.Time{}, func(t time.Time) string {
+			return t.Format("2006")
+		})
+	}
+
+	// needed delta pins the previous file of its chain, full backup ends the chain
+	need := make([]bool, len(files))
+	pinned := false
+	for i := len(files) - 1; i >= 0; i-- {
+		need[i] = keep[i] || pinned
+		pinned = need[i] && files[i].Delta
+	}
+
+	var res []string
+	for i, f := range files {
+		if !need[i] {
+			res = append(res, f.Name)
+		}
+	}
+	return res
+}
+
+// keepPeriods marks the last file of every period made not before since.
+func keepPeriods(files []snapshot.File, keep []bool, since time.Time, period func(time.Time) string) {
+	seen := map[string]bool{}
+	for i := len(files) - 1; i >= 0; i-- {
+		if files[i].Time.Before(since) {
+			return
+		}
+		if k := period(files[i].Time); !seen[k] {
+			seen[k] = true
+			keep[i] = true
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package retention
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func name(t time.Time, delta bool) string {
+	if delta {
+		return fmt.Sprintf("zen_%s.delta.json", t.Format("2006-01-02_15-04-05"))
+	}
+	return fmt.Sprintf("zen_%s.json", t.Format("2006-01-02_15-04-05"))
+}
+
+func TestPolicy_Enabled(t *testing.T) {
+	assert.False(t, Policy{}.Enabled())
+	assert.True(t, Policy{KeepLast: 1}.Enabled())
+	assert.True(t, Policy{KeepYearly: true}.Enabled())
+}
+
+func TestPolicy_PlanKeepLast(t *testing.T) {
+	now := time.Date(2024, 6, 29, 12, 0, 0, 0, time.Local)
+	names := []string{"zen_state.json", "notes.txt"}
+	for i := range 5 {
+		names = append(names, name(now.Add(-time.Duration(i)*time.Hour), false))
+	}
+
+	del := Policy{KeepLast: 2}.Plan(names, now)
+	assert.Equal(t, []string{
+		name(now.Add(-4*time.Hour), false),
+		name(now.Add(-3*time.Hour), false),
+		name(now.Add(-2*time.Hour), false),
+	}, del)
+
+	assert.Empty(t, Policy{}.Plan(names, now), "disabled policy keeps everything")
+	assert.Empty(t, Policy{KeepLast: 10}.Plan(names, now))
+}
+
+func TestPolicy_PlanGFS(t *testing.T) {
+	now := time.Date(2024, 6, 29, 23, 0, 0, 0, time.Local) // saturday
+
+	// hourly backups for two years
+	var names []string
+	for tm := now.AddDate(-2, 0, 0); !tm.After(now); tm = tm.Add(time.Hour) {
+		names = append(names, name(tm, false))
+	}
+
+	del := Policy{KeepLast: 3, KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 6, KeepYearly: true}.Plan(names, now)
+	deleted := map[string]bool{}
+	for _, d := range del {
+		deleted[d] = true
+	}
+	var kept []string
+	for _, n := range names {
+		if !deleted[n] {
+			kept = append(kept, n)
+		}
+	}
+
+	assert.Equal(t, []string{
+		"zen_2022-12-31_23-00-00.json", // yearly
+		"zen_2023-12-31_23-00-00.json", // yearly
+		"zen_2024-01-31_23-00-00.json", // monthly
+		"zen_2024-02-29_23-00-00.json", // monthly
+		"zen_2024-03-31_23-00-00.json", // monthly
+		"zen_2024-04-30_23-00-00.json", // monthly
+		"zen_2024-05-31_23-00-00.json", // monthly
+		"zen_2024-06-09_23-00-00.json", // weekly
+		"zen_2024-06-16_23-00-00.json", // weekly
+		"zen_2024-06-23_23-00-00.json", // daily, weekly
+		"zen_2024-06-24_23-00-00.json", // daily
+		"zen_2024-06-25_23-00-00.json", // daily
+		"zen_2024-06-26_23-00-00.json", // daily
+		"zen_2024-06-27_23-00-00.json", // daily
+		"zen_2024-06-28_23-00-00.json", // daily
+		"zen_2024-06-29_21-00-00.json", // last
+		"zen_2024-06-29_22-00-00.json", // last
+		"zen_2024-06-29_23-00-00.json", // last, daily, weekly, monthly, yearly
+	}, kept)
+}
+
+func TestPolicy_PlanDeltas(t *testing.T) {
+	now := time.Date(2024, 6, 29, 12, 0, 0, 0, time.Local)
+	h := func(n int) time.Time { return now.Add(-time.Duration(n) * time.Hour) }
+
+	names := []string{
+		name(h(30), false),
+		name(h(29), true),
+		name(h(28), true),
+		name(h(27), false),
+		name(h(26), true),
+		name(h(25), true), // last of yesterday
+		name(h(3), false),
+		name(h(2), true),
+		name(h(1), true),
+	}
+
+	del := Policy{KeepDaily: 2}.Plan(names, now)
+	assert.Equal(t, []string{name(h(30), false), name(h(29), true), name(h(28), true)}, del)
+
+	del = Policy{KeepLast: 1}.Plan(names, now)
+	assert.Equal(t, names[:6], del, "the whole chain of the last delta is kept")
+}
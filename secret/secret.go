@@ -0,0 +1,85 @@
+// Package secret resolves references to secrets kept out of settings, e.g. in Docker secrets
+// or a password manager, so they don't show up in process lists and unit files.
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Resolver returns secret by reference, the part of the value after the scheme.
+type Resolver func(ctx context.Context, ref string) (string, error)
+
+// Resolvers are resolvers by scheme, e.g. "file".
+type Resolvers map[string]Resolver
+
+// Default returns resolvers of references:
+//
+//	env:NAME      - value of env variable NAME
+//	file:PATH     - content of file PATH, e.g. file:/run/secrets/zen_token
+//	exec:COMMAND  - output of COMMAND, e.g. exec:pass show zenmoney. Arguments are split by spaces,
+//	                the command is run without shell
+func Default() Resolvers {
+	return Resolvers{"env": fromEnv, "file": fromFile, "exec": fromExec}
+}
+
+// Resolve returns secret referenced by value. Values without a scheme of resolvers are returned as is.
+func (rs Resolvers) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+	r, ok := rs[scheme]
+	if !ok {
+		return value, nil
+	}
+	res, err := r(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("can't resolve %s secret: %w", scheme, err)
+	}
+	return res, nil
+}
+
+func fromEnv(_ context.Context, name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+	return v, nil
+}
+
+func fromFile(_ context.Context, path string) (string, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return trim(bs), nil
+}
+
+func fromExec(ctx context.Context, command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("command is empty")
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	return trim(out), nil
+}
+
+// trim drops the trailing line break, secret files and command outputs usually end with it.
+func trim(bs []byte) string {
+	return strings.TrimRight(string(bs), "\r\n")
+}
@@ -0,0 +1,57 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolvers_Resolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("file_token\n"), 0o600))
+	t.Setenv("ZENB_TEST_TOKEN", "env_token")
+
+	rs := Default()
+	rs["static"] = func(_ context.Context, ref string) (string, error) {
+		if ref == "" {
+			return "", errors.New("ref is empty")
+		}
+		return "static_" + ref, nil
+	}
+
+	tbl := []struct {
+		value, res, err string
+	}{
+		{"plain_token", "plain_token", ""},
+		{"https://ntfy.sh/topic", "https://ntfy.sh/topic", ""},
+		{"env:ZENB_TEST_TOKEN", "env_token", ""},
+		{"env:ZENB_TEST_MISSING", "", "can't resolve env secret: ZENB_TEST_MISSING is not set"},
+		{"file:" + path, "file_token", ""},
+		{"file:" + path + ".missing", "", "can't resolve file secret: open " + path + ".missing: no such file or directory"},
+		{"exec:echo exec_token", "exec_token", ""},
+		{"exec:", "", "can't resolve exec secret: command is empty"},
+		{"exec:false", "", "can't resolve exec secret: false: exit status 1"},
+		{"static:token", "static_token", ""},
+		{"static:", "", "can't resolve static secret: ref is empty"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.value, func(t *testing.T) {
+			res, err := rs.Resolve(context.Background(), tt.value)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.res, res)
+		})
+	}
+}
+
+func TestFromExec_stderr(t *testing.T) {
+	_, err := fromExec(context.Background(), "ls /zenb-missing-dir")
+	assert.ErrorContains(t, err, "ls: exit status 2: ls: ")
+}
@@ -0,0 +1,121 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+const fileTimeLayout = "2006-01-02_15-04-05"
+
+// fileNameRe matches backup file name with optional profile, optionally followed by extensions
+// of pipeline stages, e.g. ".age".
+var fileNameRe = regexp.MustCompile(`^zen_(?:([a-z0-9][a-z0-9-]*)_)?(\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2})(\.delta)?\.json(\.[a-z0-9]+)*$`)
+
+// ErrNoBase is returned when there is no full backup to start from.
+var ErrNoBase = errors.New("no full backup found")
+
+// Source is a storage backups are read from.
+type Source interface {
+	List(ctx context.Context) ([]string, error)
+	Load(ctx context.Context, filename string) ([]byte, error)
+}
+
+// File is a parsed backup file name.
+type File struct {
+	Name    string
+	Profile string // empty for backups made without profile
+	Time    time.Time
+	Delta   bool
+}
+
+// ParseFileName parses name of a backup file made by srv.Server, with any extensions
+// added by pipeline stages. ok is false for files which are not backups, e.g. the state file.
+func ParseFileName(name string) (f File, ok bool) {
+	m := fileNameRe.FindStringSubmatch(name)
+	if m == nil {
+		return File{}, false
+	}
+	t, err := time.ParseInLocation(fileTimeLayout, m[2], time.Local)
+	if err != nil {
+		return File{}, false
+	}
+	return File{Name: name, Profile: m[1], Time: t, Delta: m[3] != ""}, true
+}
+
+// FilterProfile returns names of backups made with profile, or without profile if it's empty.
+func FilterProfile(names []string, profile string) []string {
+	var res []string
+	for _, name := range names {
+		if f, ok := ParseFileName(name); ok && f.Profile == profile {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// Chain picks the latest full backup made not after at and all deltas made after it
+// up to at, in chronological order.
+func Chain(names []string, at time.Time) (base File, deltas []File, err error) {
+	files := make([]File, 0, len(names))
+	for _, name := range names {
+		if f, ok := ParseFileName(name); ok && !f.Time.After(at) {
+			files = append(files, f)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Time.Before(files[j].Time) })
+
+	baseIdx := -1
+	for i, f := range files {
+		if !f.Delta {
+			baseIdx = i
+		}
+	}
+	if baseIdx < 0 {
+		return File{}, nil, fmt.Errorf("%w before %s", ErrNoBase, at.Format(time.RFC3339))
+	}
+	return files[baseIdx], files[baseIdx+1:], nil
+}
+
+// Restore rebuilds full data of profile for the moment at from backups kept in src.
+func Restore(ctx context.Context, src Source, profile string, at time.Time) (models.Response, error) {
+	names, err := src.List(ctx)
+	if err != nil {
+		return models.Response{}, fmt.Errorf("can't list backups: %w", err)
+	}
+	base, deltas, err := Chain(FilterProfile(names, profile), at)
+	if err != nil {
+		return models.Response{}, err
+	}
+
+	res, err := load(ctx, src, base.Name)
+	if err != nil {
+		return models.Response{}, err
+	}
+	for _, f := range deltas {
+		d, err := load(ctx, src, f.Name)
+		if err != nil {
+			return models.Response{}, err
+		}
+		res = Apply(res, d)
+	}
+	return Rebuild(res), nil
+}
+
+func load(ctx context.Context, src Source, name string) (models.Response, error) {
+	var res models.Response
+	bs, err := src.Load(ctx, name)
+	if err != nil {
+		return res, fmt.Errorf("can't load %s: %w", name, err)
+	}
+	if err := json.Unmarshal(bs, &res); err != nil {
+		return res, fmt.Errorf("can't parse %s: %w", name, err)
+	}
+	return res, nil
+}
@@ -0,0 +1,149 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type memSource map[string][]byte
+
+func (m memSource) List(_ context.Context) ([]string, error) {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res, nil
+}
+
+func (m memSource) Load(_ context.Context, filename string) ([]byte, error) {
+	bs, ok := m[filename]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return bs, nil
+}
+
+func (m memSource) add(t *testing.T, name string, r models.Response) {
+	t.Helper()
+	bs, err := json.Marshal(r)
+	assert.NoError(t, err)
+	m[name] = bs
+}
+
+func localTime(s string) time.Time {
+	t, _ := time.ParseInLocation(fileTimeLayout, s, time.Local)
+	return t
+}
+
+func TestParseFileName(t *testing.T) {
+	f, ok := ParseFileName("zen_2022-03-12_21-48-00.json")
+	assert.True(t, ok)
+	assert.Equal(t, File{Name: "zen_2022-03-12_21-48-00.json", Time: localTime("2022-03-12_21-48-00")}, f)
+
+	f, ok = ParseFileName("zen_2022-03-12_21-48-00.delta.json")
+	assert.True(t, ok)
+	assert.True(t, f.Delta)
+
+	f, ok = ParseFileName("zen_2022-03-12_21-48-00.delta.json.age")
+	assert.True(t, ok)
+	assert.True(t, f.Delta)
+	assert.Equal(t, "zen_2022-03-12_21-48-00.delta.json.age", f.Name)
+
+	f, ok = ParseFileName("zen_household_2022-03-12_21-48-00.json.zst")
+	assert.True(t, ok)
+	assert.Equal(t, File{Name: "zen_household_2022-03-12_21-48-00.json.zst", Profile: "household", Time: localTime("2022-03-12_21-48-00")}, f)
+
+	f, ok = ParseFileName("zen_small-biz_2022-03-12_21-48-00.delta.json")
+	assert.True(t, ok)
+	assert.Equal(t, "small-biz", f.Profile)
+	assert.True(t, f.Delta)
+
+	for _, name := range []string{"zen_state.json", "zen_household_state.json", "zen_Home_2022-03-12_21-48-00.json", "zen_2022-03-12.json", "notes.txt", "zen_2022-03-12_21-48-00.json.", "zen_2022-13-12_21-48-00.json"} {
+		_, ok = ParseFileName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestChain(t *testing.T) {
+	names := []string{
+		"zen_state.json",
+		"zen_2022-03-12_10-00-00.delta.json",
+		"zen_2022-03-12_12-00-00.json",
+		"zen_2022-03-12_13-00-00.delta.json",
+		"zen_2022-03-12_14-00-00.delta.json",
+		"zen_2022-03-12_15-00-00.json",
+		"zen_2022-03-12_16-00-00.delta.json",
+	}
+
+	base, deltas, err := Chain(names, localTime("2022-03-12_14-30-00"))
+	assert.NoError(t, err)
+	assert.Equal(t, "zen_2022-03-12_12-00-00.json", base.Name)
+	assert.Len(t, deltas, 2)
+	assert.Equal(t, "zen_2022-03-12_13-00-00.delta.json", deltas[0].Name)
+	assert.Equal(t, "zen_2022-03-12_14-00-00.delta.json", deltas[1].Name)
+
+	base, deltas, err = Chain(names, localTime("2022-03-12_15-00-00"))
+	assert.NoError(t, err)
+	assert.Equal(t, "zen_2022-03-12_15-00-00.json", base.Name)
+	assert.Empty(t, deltas)
+
+	_, _, err = Chain(names, localTime("2022-03-12_11-00-00"))
+	assert.ErrorIs(t, err, ErrNoBase)
+}
+
+func TestFilterProfile(t *testing.T) {
+	names := []string{
+		"zen_state.json",
+		"zen_2022-03-12_10-00-00.json",
+		"zen_household_state.json",
+		"zen_household_2022-03-12_10-00-00.json",
+		"zen_household_2022-03-12_11-00-00.delta.json.age",
+		"zen_biz_2022-03-12_10-00-00.json",
+	}
+	assert.Equal(t, []string{"zen_2022-03-12_10-00-00.json"}, FilterProfile(names, ""))
+	assert.Equal(t, []string{"zen_household_2022-03-12_10-00-00.json", "zen_household_2022-03-12_11-00-00.delta.json.age"},
+		FilterProfile(names, "household"))
+	assert.Empty(t, FilterProfile(names, "other"))
+}
+
+func TestRestore(t *testing.T) {
+	src := memSource{}
+	src.add(t, "zen_2022-03-12_12-00-00.json", models.Response{
+		ServerTimestamp: 100,
+		Tag:             []models.Tag{{ID: "t1", Title: "Food", Changed: 10}},
+	})
+	src.add(t, "zen_2022-03-12_13-00-00.delta.json", models.Response{
+		ServerTimestamp: 200,
+		Tag:             []models.Tag{{ID: "t1", Title: "Groceries", Changed: 150}},
+	})
+	src.add(t, "zen_2022-03-12_14-00-00.delta.json", models.Response{
+		ServerTimestamp: 300,
+		Deletion:        []models.Deletion{{ID: "t1", Object: "tag", Stamp: 250}},
+	})
+
+	res, err := Restore(context.Background(), src, "", localTime("2022-03-12_13-30-00"))
+	assert.NoError(t, err)
+	assert.Equal(t, models.Response{
+		ServerTimestamp: 200,
+		Tag:             []models.Tag{{ID: "t1", Title: "Groceries", Changed: 150}},
+	}, res)
+
+	res, err = Restore(context.Background(), src, "", localTime("2022-03-12_14-00-00"))
+	assert.NoError(t, err)
+	assert.Equal(t, models.Response{ServerTimestamp: 300}, res)
+
+	src.add(t, "zen_biz_2022-03-12_14-30-00.json", models.Response{ServerTimestamp: 1000})
+	res, err = Restore(context.Background(), src, "biz", localTime("2022-03-12_15-00-00"))
+	assert.NoError(t, err)
+	assert.Equal(t, models.Response{ServerTimestamp: 1000}, res)
+
+	src["zen_2022-03-12_15-00-00.delta.json"] = []byte("{")
+	_, err = Restore(context.Background(), src, "", localTime("2022-03-12_15-00-00"))
+	assert.ErrorContains(t, err, "can't parse zen_2022-03-12_15-00-00.delta.json")
+}
@@ -0,0 +1,53 @@
+package snapshot
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// Normalize returns copy of r with server timestamp dropped and all entities sorted by id,
+// so the same data always has the same representation.
+func Normalize(r models.Response) models.Response {
+	return models.Response{
+		Instrument:     sorted(r.Instrument, func(v models.Instrument) int { return v.ID }),
+		Country:        sorted(r.Country, func(v models.Country) int { return v.ID }),
+		Company:        sorted(r.Company, func(v models.Company) int { return v.ID }),
+		User:           sorted(r.User, func(v models.User) int { return v.ID }),
+		Account:        sorted(r.Account, func(v models.Account) string { return v.ID }),
+		Tag:            sorted(r.Tag, func(v models.Tag) string { return v.ID }),
+		Merchant:       sorted(r.Merchant, func(v models.Merchant) string { return v.ID }),
+		Budget:         sorted(r.Budget, func(v models.Budget) string { k := budgetKey(v); return k[0] + "/" + k[1] }),
+		Reminder:       sorted(r.Reminder, func(v models.Reminder) string { return v.ID }),
+		ReminderMarker: sorted(r.ReminderMarker, func(v models.ReminderMarker) string { return v.ID }),
+		Transaction:    sorted(r.Transaction, func(v models.Transaction) string { return v.ID }),
+		Deletion:       sorted(r.Deletion, func(v models.Deletion) string { return v.Object + "/" + v.ID }),
+	}
+}
+
+// Hash returns hex encoded sha256 of normalized r.
+func Hash(r models.Response) (string, error) {
+	bs, err := json.Marshal(Normalize(r))
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(bs)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// IsEmpty returns true if r has no entities, e.g. delta made when nothing changed.
+func IsEmpty(r models.Response) bool {
+	return len(r.Instrument) == 0 && len(r.Country) == 0 && len(r.Company) == 0 && len(r.User) == 0 &&
+		len(r.Account) == 0 && len(r.Tag) == 0 && len(r.Merchant) == 0 && len(r.Budget) == 0 &&
+		len(r.Reminder) == 0 && len(r.ReminderMarker) == 0 && len(r.Transaction) == 0 && len(r.Deletion) == 0
+}
+
+func sorted[T any, K cmp.Ordered](items []T, key func(T) K) []T {
+	res := slices.Clone(items)
+	slices.SortStableFunc(res, func(a, b T) int { return cmp.Compare(key(a), key(b)) })
+	return res
+}
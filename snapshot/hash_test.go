@@ -0,0 +1,43 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	r := models.Response{
+		ServerTimestamp: 100,
+		Tag:             []models.Tag{{ID: "b"}, {ID: "a"}},
+		Budget:          []models.Budget{{Tag: strPtr("t1"), Date: "2024-02-01"}, {Tag: strPtr("t1"), Date: "2024-01-01"}},
+	}
+
+	n := Normalize(r)
+	assert.Zero(t, n.ServerTimestamp)
+	assert.Equal(t, []models.Tag{{ID: "a"}, {ID: "b"}}, n.Tag)
+	assert.Equal(t, "2024-01-01", n.Budget[0].Date)
+	assert.Nil(t, n.Transaction)
+	assert.Equal(t, "b", r.Tag[0].ID, "original is untouched")
+}
+
+func TestHash(t *testing.T) {
+	h1, err := Hash(models.Response{ServerTimestamp: 100, Tag: []models.Tag{{ID: "b"}, {ID: "a"}}})
+	assert.NoError(t, err)
+	assert.Len(t, h1, 64)
+
+	h2, err := Hash(models.Response{ServerTimestamp: 200, Tag: []models.Tag{{ID: "a"}, {ID: "b"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, h1, h2, "order and server timestamp are ignored")
+
+	h3, err := Hash(models.Response{ServerTimestamp: 200, Tag: []models.Tag{{ID: "a", Title: "Food"}, {ID: "b"}}})
+	assert.NoError(t, err)
+	assert.NotEqual(t, h1, h3)
+}
+
+func TestIsEmpty(t *testing.T) {
+	assert.True(t, IsEmpty(models.Response{ServerTimestamp: 100}))
+	assert.False(t, IsEmpty(models.Response{Deletion: []models.Deletion{{ID: "a"}}}))
+	assert.False(t, IsEmpty(models.Response{Transaction: []models.Transaction{{ID: "a"}}}))
+}
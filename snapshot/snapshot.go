@@ -0,0 +1,126 @@
+// Package snapshot rebuilds full ZenMoney data for a point in time from a base full backup
+// and a chain of incremental (delta) backups.
+package snapshot
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// Rebuild replays deltas over base, in order, and returns data in the same shape
+// as FullSync would have returned it at the moment of the last delta.
+func Rebuild(base models.Response, deltas ...models.Response) models.Response {
+	res := base
+	for _, d := range deltas {
+		res = Apply(res, d)
+	}
+	res.Deletion = nil
+	return res
+}
+
+// Apply merges delta into base. Entities are upserted by id, an entity already present
+// in base is replaced only if the delta one has the same or newer changed stamp.
+// Deletion records of the delta remove the referenced entities, budget is referenced by its tag and month.
+func Apply(base, delta models.Response) models.Response {
+	res := models.Response{
+		ServerTimestamp: max(base.ServerTimestamp, delta.ServerTimestamp),
+		Instrument:      upsert(base.Instrument, delta.Instrument, func(v models.Instrument) int { return v.ID }, func(v models.Instrument) int { return v.Changed }),
+		Country:         upsert(base.Country, delta.Country, func(v models.Country) int { return v.ID }, func(models.Country) int { return 0 }),
+		Company:         upsert(base.Company, delta.Company, func(v models.Company) int { return v.ID }, func(v models.Company) int { return v.Changed }),
+		User:            upsert(base.User, delta.User, func(v models.User) int { return v.ID }, func(v models.User) int { return v.Changed }),
+		Account:         upsert(base.Account, delta.Account, func(v models.Account) string { return v.ID }, func(v models.Account) int { return v.Changed }),
+		Tag:             upsert(base.Tag, delta.Tag, func(v models.Tag) string { return v.ID }, func(v models.Tag) int { return v.Changed }),
+		Merchant:        upsert(base.Merchant, delta.Merchant, func(v models.Merchant) string { return v.ID }, func(v models.Merchant) int { return v.Changed }),
+		Budget:          upsert(base.Budget, delta.Budget, budgetKey, func(v models.Budget) int { return v.Changed }),
+		Reminder:        upsert(base.Reminder, delta.Reminder, func(v models.Reminder) string { return v.ID }, func(v models.Reminder) int { return v.Changed }),
+		ReminderMarker:  upsert(base.ReminderMarker, delta.ReminderMarker, func(v models.ReminderMarker) string { return v.ID }, func(v models.ReminderMarker) int { return v.Changed }),
+		Transaction:     upsert(base.Transaction, delta.Transaction, func(v models.Transaction) string { return v.ID }, func(v models.Transaction) int { return v.Changed }),
+		Deletion:        slices.Concat(base.Deletion, delta.Deletion),
+	}
+
+	if len(delta.Deletion) == 0 {
+		return res
+	}
+
+	// deletion ids by object class
+	deleted := map[string]map[string]bool{}
+	for _, d := range delta.Deletion {
+		if deleted[d.Object] == nil {
+			deleted[d.Object] = map[string]bool{}
+		}
+		deleted[d.Object][d.ID] = true
+	}
+
+	res.Instrument = remove(res.Instrument, deleted[string(models.EntityTypeInstrument)], func(v models.Instrument) string { return strconv.Itoa(v.ID) })
+	res.Company = remove(res.Company, deleted[string(models.EntityTypeCompany)], func(v models.Company) string { return strconv.Itoa(v.ID) })
+	res.User = remove(res.User, deleted[string(models.EntityTypeUser)], func(v models.User) string { return strconv.Itoa(v.ID) })
+	res.Account = remove(res.Account, deleted[string(models.EntityTypeAccount)], func(v models.Account) string { return v.ID })
+	res.Tag = remove(res.Tag, deleted[string(models.EntityTypeTag)], func(v models.Tag) string { return v.ID })
+	res.Merchant = remove(res.Merchant, deleted[string(models.EntityTypeMerchant)], func(v models.Merchant) string { return v.ID })
+	res.Budget = remove(res.Budget, deleted[string(models.EntityTypeBudget)], budgetID)
+	res.Reminder = remove(res.Reminder, deleted[string(models.EntityTypeReminder)], func(v models.Reminder) string { return v.ID })
+	res.ReminderMarker = remove(res.ReminderMarker, deleted[string(models.EntityTypeReminderMarker)], func(v models.ReminderMarker) string { return v.ID })
+	res.Transaction = remove(res.Transaction, deleted[string(models.EntityTypeTransaction)], func(v models.Transaction) string { return v.ID })
+	return res
+}
+
+// budgetKey identifies budget, which has no id of its own, by its tag and month.
+func budgetKey(b models.Budget) [2]string {
+	tag := ""
+	if b.Tag != nil {
+		tag = *b.Tag
+	}
+	return [2]string{tag, b.Date}
+}
+
+// budgetID returns id referencing budget in deletions, its tag and month joined by slash,
+// e.g. "food/2024-03-01", tag is empty for the budget of all categories.
+func budgetID(b models.Budget) string {
+	k := budgetKey(b)
+	return k[0] + "/" + k[1]
+}
+
+// upsert returns items updated with updates, matched by key. New items are appended
+// to the end keeping their order, existing ones are updated in place.
+func upsert[T any, K comparable](items, updates []T, key func(T) K, changed func(T) int) []T {
+	if len(updates) == 0 {
+		return items
+	}
+
+	res := make([]T, len(items), len(items)+len(updates))
+	copy(res, items)
+
+	idx := make(map[K]int, len(res))
+	for i, v := range res {
+		idx[key(v)] = i
+	}
+	for _, v := range updates {
+		i, ok := idx[key(v)]
+		if !ok {
+			idx[key(v)] = len(res)
+			res = append(res, v)
+			continue
+		}
+		if changed(v) >= changed(res[i]) {
+			res[i] = v
+		}
+	}
+	return res
+}
+
+// remove returns items without ones whose id is in ids.
+func remove[T any](items []T, ids map[string]bool, id func(T) string) []T {
+	if len(ids) == 0 {
+		return items
+	}
+
+	var res []T
+	for _, v := range items {
+		if !ids[id(v)] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
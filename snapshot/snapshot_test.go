@@ -0,0 +1,122 @@
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestApply(t *testing.T) {
+	base := models.Response{
+		ServerTimestamp: 100,
+		Instrument:      []models.Instrument{{ID: 1, ShortTitle: "RUB", Changed: 10}},
+		Account:         []models.Account{{ID: "a1", Title: "Cash", Changed: 10}, {ID: "a2", Title: "Card", Changed: 10}},
+		Tag:             []models.Tag{{ID: "t1", Title: "Food", Changed: 10}},
+		Budget:          []models.Budget{{Tag: strPtr("t1"), Date: "2024-01-01", Outcome: 100, Changed: 10}},
+		Transaction: []models.Transaction{
+			{ID: "tx1", Outcome: 10, Changed: 10},
+			{ID: "tx2", Outcome: 20, Changed: 50},
+		},
+	}
+	delta := models.Response{
+		ServerTimestamp: 200,
+		Instrument:      []models.Instrument{{ID: 1, ShortTitle: "RUB", Changed: 10}},
+		Account:         []models.Account{{ID: "a1", Title: "Wallet", Changed: 150}},
+		Budget: []models.Budget{
+			{Tag: strPtr("t1"), Date: "2024-01-01", Outcome: 200, Changed: 150},
+			{Tag: strPtr("t1"), Date: "2024-02-01", Outcome: 300, Changed: 150},
+		},
+		Transaction: []models.Transaction{
+			{ID: "tx2", Outcome: 25, Changed: 40}, // older than the one in base
+			{ID: "tx3", Outcome: 30, Changed: 150},
+		},
+		Deletion: []models.Deletion{
+			{ID: "tx1", Object: "transaction", Stamp: 150},
+			{ID: "a2", Object: "account", Stamp: 150},
+			{ID: "t1", Object: "merchant", Stamp: 150}, // same id, other class
+		},
+	}
+
+	res := Apply(base, delta)
+	assert.Equal(t, 200, res.ServerTimestamp)
+	assert.Equal(t, base.Instrument, res.Instrument)
+	assert.Equal(t, []models.Account{{ID: "a1", Title: "Wallet", Changed: 150}}, res.Account)
+	assert.Equal(t, base.Tag, res.Tag)
+	assert.Equal(t, delta.Budget, res.Budget)
+	assert.Equal(t, []models.Transaction{{ID: "tx2", Outcome: 20, Changed: 50}, {ID: "tx3", Outcome: 30, Changed: 150}}, res.Transaction)
+	assert.Equal(t, delta.Deletion, res.Deletion)
+
+	// base is untouched
+	assert.Len(t, base.Transaction, 2)
+	assert.Equal(t, "Cash", base.Account[0].Title)
+}
+
+func TestRebuild(t *testing.T) {
+	base := models.Response{
+		ServerTimestamp: 100,
+		Transaction:     []models.Transaction{{ID: "tx1", Changed: 10}},
+	}
+	d1 := models.Response{
+		ServerTimestamp: 200,
+		Transaction:     []models.Transaction{{ID: "tx2", Changed: 150}},
+	}
+	d2 := models.Response{
+		ServerTimestamp: 300,
+		Deletion:        []models.Deletion{{ID: "tx1", Object: "transaction", Stamp: 250}},
+	}
+
+	res := Rebuild(base, d1, d2)
+	assert.Equal(t, models.Response{
+		ServerTimestamp: 300,
+		Transaction:     []models.Transaction{{ID: "tx2", Changed: 150}},
+	}, res)
+
+	assert.Equal(t, base, Rebuild(base))
+}
+
+func TestApply_Deletion(t *testing.T) {
+	base := models.Response{
+		Instrument:     []models.Instrument{{ID: 1}, {ID: 2}},
+		Company:        []models.Company{{ID: 1}, {ID: 2}},
+		User:           []models.User{{ID: 1}, {ID: 2}},
+		Account:        []models.Account{{ID: "1"}, {ID: "2"}},
+		Tag:            []models.Tag{{ID: "1"}, {ID: "2"}},
+		Merchant:       []models.Merchant{{ID: "1"}, {ID: "2"}},
+		Budget:         []models.Budget{{Tag: strPtr("1"), Date: "2024-01-01"}, {Date: "2024-01-01"}},
+		Reminder:       []models.Reminder{{ID: "1"}, {ID: "2"}},
+		ReminderMarker: []models.ReminderMarker{{ID: "1"}, {ID: "2"}},
+		Transaction:    []models.Transaction{{ID: "1"}, {ID: "2"}},
+	}
+
+	tbl := []struct {
+		object string
+		id     string
+		want   func(r *models.Response) // base without the deleted entity
+	}{
+		{"instrument", "1", func(r *models.Response) { r.Instrument = []models.Instrument{{ID: 2}} }},
+		{"company", "1", func(r *models.Response) { r.Company = []models.Company{{ID: 2}} }},
+		{"user", "1", func(r *models.Response) { r.User = []models.User{{ID: 2}} }},
+		{"account", "1", func(r *models.Response) { r.Account = []models.Account{{ID: "2"}} }},
+		{"tag", "1", func(r *models.Response) { r.Tag = []models.Tag{{ID: "2"}} }},
+		{"merchant", "1", func(r *models.Response) { r.Merchant = []models.Merchant{{ID: "2"}} }},
+		{"budget", "1/2024-01-01", func(r *models.Response) { r.Budget = []models.Budget{{Date: "2024-01-01"}} }},
+		{"budget", "/2024-01-01", func(r *models.Response) { r.Budget = []models.Budget{{Tag: strPtr("1"), Date: "2024-01-01"}} }},
+		{"reminder", "1", func(r *models.Response) { r.Reminder = []models.Reminder{{ID: "2"}} }},
+		{"reminderMarker", "1", func(r *models.Response) { r.ReminderMarker = []models.ReminderMarker{{ID: "2"}} }},
+		{"transaction", "1", func(r *models.Response) { r.Transaction = []models.Transaction{{ID: "2"}} }},
+		{"unknown", "1", func(*models.Response) {}},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.object+" "+tt.id, func(t *testing.T) {
+			delta := models.Response{Deletion: []models.Deletion{{ID: tt.id, Object: tt.object}}}
+			want := base
+			tt.want(&want)
+			want.Deletion = delta.Deletion
+			assert.Equal(t, want, Apply(base, delta))
+		})
+	}
+}
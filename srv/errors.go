@@ -0,0 +1,45 @@
+package srv
+
+import "fmt"
+
+// Stage is a step of backup run.
+type Stage string
+
+// Stages of backup run, see StageError.
+const (
+	StageClient    Stage = "client"    // API client creation
+	StageExport    Stage = "export"    // downloading data from ZenMoney
+	StageEncode    Stage = "encode"    // marshaling, compression and encryption
+	StageSave      Stage = "save"      // writing backup or state to the storage
+	StageMirror    Stage = "mirror"    // updating mirror of data, e.g. SQLite database
+	StageRetention Stage = "retention" // pruning of old backups
+	StageNotify    Stage = "notify"    // sending notifications, never fails a backup
+)
+
+// StageError is an error of a backup run with the stage it happened at.
+type StageError struct {
+	Stage Stage
+	Err   error
+
+	saved bool // backup file was saved before the error, so the run isn't repeated
+}
+
+// Error returns stage and message of the underlying error.
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Stage, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// stageTitles are notification titles by stage.
+var stageTitles = map[Stage]string{
+	StageClient:    "Client Creation Error",
+	StageExport:    "Backup Export Error",
+	StageEncode:    "Backup Export Error",
+	StageSave:      "Backup Save Error",
+	StageMirror:    "Backup Mirror Error",
+	StageRetention: "Backup Retention Error",
+}
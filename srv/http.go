@@ -0,0 +1,130 @@
+package srv
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/go-pkgz/lgr"
+)
+
+// HTTPOpts are settings of the embedded HTTP server.
+type HTTPOpts struct {
+	Addr        string
+	ReadyMaxAge time.Duration // /readyz fails if the last successful backup is older, zero disables the check
+	APIToken    string        // bearer token of POST /backup, the endpoint is disabled if empty
+}
+
+// ListenAndServe serves health, status and control endpoints of servers, one per profile,
+// until ctx is canceled:
+//
+//	GET /healthz - liveness, always ok while the process is running
+//	GET /readyz  - API clients are created and the last successful backups are not older than ReadyMaxAge
+//	GET /status  - Status as JSON, or object of them by profile name if profiles are used
+//	GET /metrics - metrics in Prometheus text format
+//	POST /backup - request a backup out of schedule, see Server.Trigger. All profiles are backed up,
+//	               unless the profile query parameter is set
+func ListenAndServe(ctx context.Context, opts HTTPOpts, servers ...*Server) error {
+	httpSrv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler(opts, servers...),
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[WARN] http server shutdown failed: %s", err)
+		}
+	}()
+
+	log.Printf("[INFO] http server listens on %s", opts.Addr)
+	if err := httpSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func handler(opts HTTPOpts, servers ...*Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		var errs []string
+		for _, s := range servers {
+			if err := s.Status().ready(s.clock.Now(), opts.ReadyMaxAge); err != nil {
+				errs = append(errs, withProfile(s.profile, err.Error()))
+			}
+		}
+		if len(errs) > 0 {
+			http.Error(w, strings.Join(errs, "\n"), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, _ *http.Request) {
+		var res any
+		if len(servers) == 1 && servers[0].profile == "" {
+			res = servers[0].Status()
+		} else {
+			byProfile := make(map[string]Status, len(servers))
+			for _, s := range servers {
+				byProfile[s.profile] = s.Status()
+			}
+			res = byProfile
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			log.Printf("[WARN] failed to write status: %s", err)
+		}
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := writeMetrics(w, servers); err != nil {
+			log.Printf("[WARN] failed to write metrics: %s", err)
+		}
+	})
+	if opts.APIToken != "" {
+		mux.HandleFunc("POST /backup", func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(opts.APIToken)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			profile, found, triggered := r.URL.Query().Get("profile"), false, false
+			for _, s := range servers {
+				if profile == "" || s.profile == profile {
+					found = true
+					triggered = s.Trigger() || triggered
+				}
+			}
+			switch {
+			case !found:
+				http.Error(w, "unknown profile", http.StatusNotFound)
+			case !triggered:
+				http.Error(w, "backup is already pending", http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte("backup is triggered\n"))
+			}
+		})
+	}
+	return mux
+}
+
+// withProfile prefixes msg with profile name, if it's set.
+func withProfile(profile, msg string) string {
+	if profile == "" {
+		return msg
+	}
+	return profile + ": " + msg
+}
@@ -0,0 +1,173 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_handler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 10, cancel: cancel}
+	client := &syncerMock{}
+	s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{}, WithClock(clock))
+	ts := httptest.NewServer(handler(HTTPOpts{ReadyMaxAge: 2 * time.Hour}, s))
+	defer ts.Close()
+
+	get := func(path string) (int, string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+path, http.NoBody)
+		assert.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		defer resp.Body.Close()
+		bs, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return resp.StatusCode, string(bs)
+	}
+
+	code, body := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok\n", body)
+
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "api client is not created\n", body)
+
+	s.client = client
+	assert.NoError(t, s.RunOnce(ctx))
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body = get("/status")
+	assert.Equal(t, http.StatusOK, code)
+	var st Status
+	assert.NoError(t, json.Unmarshal([]byte(body), &st))
+	assert.Equal(t, Status{
+		ClientReady: true,
+		LastSuccess: clock.now,
+		LastFile:    "zen_2024-06-29_15-30-00.json",
+		LastSize:    st.LastSize,
+	}, st)
+	assert.Positive(t, st.LastSize)
+
+	code, body = get("/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "zenmoney_backup_successes_total 1\n")
+
+	// backup is failing for too long
+	clock.now = clock.now.Add(3 * time.Hour)
+	client.err = errors.New("INVALID_TOKEN: token is not provided")
+	assert.Error(t, s.RunOnce(ctx))
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "last successful backup is 3h0m0s old\n", body)
+
+	_, body = get("/status")
+	assert.Contains(t, body, `"lastError":"export: INVALID_TOKEN: token is not provided"`)
+	assert.Contains(t, body, `"lastFailure":"2024-06-29T18:30:00Z"`)
+}
+
+func TestServer_ListenAndServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{})
+
+	done := make(chan error)
+	go func() { done <- ListenAndServe(ctx, HTTPOpts{Addr: "127.0.0.1:0"}, s) }()
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server is not stopped")
+	}
+}
+
+func TestServer_handlerBackup(t *testing.T) {
+	s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{})
+
+	post := func(h http.Handler, token string) (int, string) {
+		req := httptest.NewRequest(http.MethodPost, "/backup", http.NoBody)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, _ := post(handler(HTTPOpts{}, s), "secret")
+	assert.Equal(t, http.StatusNotFound, code, "disabled without api token")
+
+	h := handler(HTTPOpts{APIToken: "secret"}, s)
+	code, _ = post(h, "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = post(h, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, body := post(h, "secret")
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, "backup is triggered\n", body)
+	code, body = post(h, "secret")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "backup is already pending\n", body)
+
+	req := httptest.NewRequest(http.MethodGet, "/backup", http.NoBody)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestServer_handlerProfiles(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC)}
+	home := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{},
+		WithClock(clock), WithProfile("home"))
+	home.client = &syncerMock{}
+	assert.NoError(t, home.RunOnce(context.Background()))
+	work := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{},
+		WithClock(clock), WithProfile("work"))
+	h := handler(HTTPOpts{APIToken: "secret"}, home, work)
+
+	do := func(method, path string) (int, string) {
+		req := httptest.NewRequest(method, path, http.NoBody)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := do(http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "work: api client is not created\n", body)
+
+	code, body = do(http.MethodGet, "/status")
+	assert.Equal(t, http.StatusOK, code)
+	var st map[string]Status
+	assert.NoError(t, json.Unmarshal([]byte(body), &st))
+	assert.Equal(t, "zen_home_2024-06-29_15-30-00.json", st["home"].LastFile)
+	assert.False(t, st["work"].ClientReady)
+
+	_, body = do(http.MethodGet, "/metrics")
+	assert.Contains(t, body, `zenmoney_backup_successes_total{profile="home"} 1`+"\n")
+	assert.Contains(t, body, `zenmoney_backup_successes_total{profile="work"} 0`+"\n")
+
+	code, _ = do(http.MethodPost, "/backup?profile=unknown")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = do(http.MethodPost, "/backup?profile=work")
+	assert.Equal(t, http.StatusAccepted, code)
+	code, _ = do(http.MethodPost, "/backup?profile=work")
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = do(http.MethodPost, "/backup")
+	assert.Equal(t, http.StatusAccepted, code, "home is triggered")
+	code, _ = do(http.MethodPost, "/backup")
+	assert.Equal(t, http.StatusConflict, code)
+}
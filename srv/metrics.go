@@ -0,0 +1,237 @@
+package srv
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+)
+
+// metrics of backup runs, exposed in Prometheus text format. Client library is not used
+// to keep dependencies small, the format is simple enough.
+type metrics struct {
+	mu sync.Mutex
+
+	attempts    int
+	successes   int
+	skipped     int
+	failures    map[Stage]int
+	syncSeconds map[string]*histogram // by sync type, full or delta
+	exportBytes *histogram
+	lastSuccess time.Time
+	entities    map[string]int // by entity type, of the last full backup
+}
+
+var (
+	syncBuckets = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	sizeBuckets = []float64{1 << 16, 1 << 18, 1 << 20, 1 << 22, 1 << 24, 1 << 26, 1 << 28}
+)
+
+func newMetrics() *metrics {
+	m := &metrics{
+		failures:    map[Stage]int{},
+		syncSeconds: map[string]*histogram{"full": newHistogram(syncBuckets), "delta": newHistogram(syncBuckets)},
+		exportBytes: newHistogram(sizeBuckets),
+		entities:    map[string]int{},
+	}
+	// report zero failures of every stage, so rate() works from the first failure
+	for _, s := range []Stage{StageClient, StageExport, StageEncode, StageSave, StageMirror, StageRetention, StageNotify} {
+		m.failures[s] = 0
+	}
+	return m
+}
+
+func (m *metrics) attempt() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+}
+
+func (m *metrics) success(t time.Time, skipped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.successes++
+	if skipped {
+		m.skipped++
+	}
+	m.lastSuccess = t
+}
+
+func (m *metrics) failure(s Stage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[s]++
+}
+
+func (m *metrics) observeSync(full bool, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	typ := "delta"
+	if full {
+		typ = "full"
+	}
+	m.syncSeconds[typ].observe(d.Seconds())
+}
+
+func (m *metrics) observeSize(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exportBytes.observe(float64(n))
+}
+
+func (m *metrics) setEntities(r models.Response) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entities = map[string]int{
+		"instrument":      len(r.Instrument),
+		"country":         len(r.Country),
+		"company":         len(r.Company),
+		"user":            len(r.User),
+		"account":         len(r.Account),
+		"tag":             len(r.Tag),
+		"merchant":        len(r.Merchant),
+		"budget":          len(r.Budget),
+		"reminder":        len(r.Reminder),
+		"reminder_marker": len(r.ReminderMarker),
+		"transaction":     len(r.Transaction),
+	}
+}
+
+// writeMetrics writes metrics of servers in Prometheus text exposition format,
+// labeled by profile if it's set.
+func writeMetrics(w io.Writer, servers []*Server) error {
+	p := &promWriter{w: w}
+	for _, f := range families {
+		p.printf("# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+		for _, s := range servers {
+			labels := ""
+			if s.profile != "" {
+				labels = label("profile", s.profile)
+			}
+			s.metrics.mu.Lock()
+			f.write(p, s.metrics, f.name, labels)
+			s.metrics.mu.Unlock()
+		}
+	}
+	return p.err
+}
+
+// family is a metric with all its samples.
+type family struct {
+	name, typ, help string
+	write           func(p *promWriter, m *metrics, name, labels string)
+}
+
+var families = []family{
+	{"zenmoney_backup_attempts_total", "counter", "Backup attempts, including retries. No stage label: the stage is known only when an attempt fails.",
+		func(p *promWriter, m *metrics, name, labels string) { p.sample(name, labels, float64(m.attempts)) }},
+	{"zenmoney_backup_successes_total", "counter", "Successful backups, including skipped unchanged ones. No stage label: a success passes every stage.",
+		func(p *promWriter, m *metrics, name, labels string) { p.sample(name, labels, float64(m.successes)) }},
+	{"zenmoney_backup_skipped_total", "counter", "Backups skipped as unchanged.",
+		func(p *promWriter, m *metrics, name, labels string) { p.sample(name, labels, float64(m.skipped)) }},
+	{"zenmoney_backup_failures_total", "counter", "Failed backup attempts by the stage they failed at, failed notifications have stage notify.",
+		func(p *promWriter, m *metrics, name, labels string) {
+			for _, s := range sortedKeys(m.failures) {
+				p.sample(name, joinLabels(labels, label("stage", string(s))), float64(m.failures[s]))
+			}
+		}},
+	{"zenmoney_backup_sync_duration_seconds", "histogram", "Duration of ZenMoney API sync requests.",
+		func(p *promWriter, m *metrics, name, labels string) {
+			for _, typ := range sortedKeys(m.syncSeconds) {
+				m.syncSeconds[typ].write(p, name, joinLabels(labels, label("type", typ)))
+			}
+		}},
+	{"zenmoney_backup_export_size_bytes", "histogram", "Size of saved backup files.",
+		func(p *promWriter, m *metrics, name, labels string) { m.exportBytes.write(p, name, labels) }},
+	{"zenmoney_backup_last_success_timestamp_seconds", "gauge", "Unix time of the last successful backup.",
+		func(p *promWriter, m *metrics, name, labels string) {
+			v := 0.0
+			if !m.lastSuccess.IsZero() {
+				v = float64(m.lastSuccess.Unix())
+			}
+			p.sample(name, labels, v)
+		}},
+	{"zenmoney_backup_entities", "gauge", "Number of entities in the last full backup by type.",
+		func(p *promWriter, m *metrics, name, labels string) {
+			for _, typ := range sortedKeys(m.entities) {
+				p.sample(name, joinLabels(labels, label("type", typ)), float64(m.entities[typ]))
+			}
+		}},
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []int // cumulative, by bucket
+	sum     float64
+	count   int
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]int, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *histogram) write(p *promWriter, name, labels string) {
+	for i, b := range h.buckets {
+		p.sample(name+"_bucket", joinLabels(labels, label("le", formatFloat(b))), float64(h.counts[i]))
+	}
+	p.sample(name+"_bucket", joinLabels(labels, label("le", "+Inf")), float64(h.count))
+	p.sample(name+"_sum", labels, h.sum)
+	p.sample(name+"_count", labels, float64(h.count))
+}
+
+// promWriter writes lines of text exposition format, keeping the first error.
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) sample(name, labels string, v float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	p.printf("%s%s %s\n", name, labels, formatFloat(v))
+}
+
+func (p *promWriter) printf(format string, args ...any) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func label(name, value string) string {
+	return name + "=" + strconv.Quote(value)
+}
+
+func joinLabels(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	res := make([]K, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	slices.Sort(res)
+	return res
+}
@@ -0,0 +1,96 @@
+package srv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	m := newMetrics()
+	m.attempt()
+	m.attempt()
+	m.failure(StageExport)
+	m.success(time.Unix(1719664245, 0), false)
+	m.observeSync(true, 1500*time.Millisecond)
+	m.observeSize(100000)
+	m.setEntities(models.Response{Transaction: make([]models.Transaction, 3), Account: make([]models.Account, 2)})
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, writeMetrics(&buf, []*Server{{metrics: m}}))
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE zenmoney_backup_attempts_total counter",
+		"zenmoney_backup_attempts_total 2",
+		"zenmoney_backup_successes_total 1",
+		"zenmoney_backup_skipped_total 0",
+		`zenmoney_backup_failures_total{stage="export"} 1`,
+		`zenmoney_backup_failures_total{stage="save"} 0`,
+		`zenmoney_backup_failures_total{stage="notify"} 0`,
+		"# TYPE zenmoney_backup_sync_duration_seconds histogram",
+		`zenmoney_backup_sync_duration_seconds_bucket{type="full",le="1"} 0`,
+		`zenmoney_backup_sync_duration_seconds_bucket{type="full",le="2.5"} 1`,
+		`zenmoney_backup_sync_duration_seconds_bucket{type="full",le="+Inf"} 1`,
+		`zenmoney_backup_sync_duration_seconds_sum{type="full"} 1.5`,
+		`zenmoney_backup_sync_duration_seconds_count{type="delta"} 0`,
+		`zenmoney_backup_export_size_bytes_bucket{le="65536"} 0`,
+		`zenmoney_backup_export_size_bytes_bucket{le="262144"} 1`,
+		"zenmoney_backup_export_size_bytes_sum 100000",
+		"zenmoney_backup_last_success_timestamp_seconds 1.719664245e+09",
+		`zenmoney_backup_entities{type="transaction"} 3`,
+		`zenmoney_backup_entities{type="account"} 2`,
+		`zenmoney_backup_entities{type="tag"} 0`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
+
+func TestServer_metrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 10, cancel: cancel}
+	client := &syncerMock{err: errors.New("SERVER_ERROR: server returned error status: 503"), failures: 1}
+	s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{},
+		WithClock(clock), WithRetry(RetryPolicy{MaxAttempts: 2, Delay: time.Second}))
+	s.client = client
+	assert.NoError(t, s.RunOnce(ctx))
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, writeMetrics(&buf, []*Server{s}))
+	out := buf.String()
+	assert.Contains(t, out, "zenmoney_backup_attempts_total 2\n")
+	assert.Contains(t, out, "zenmoney_backup_successes_total 1\n")
+	assert.Contains(t, out, `zenmoney_backup_failures_total{stage="export"} 1`+"\n")
+	assert.Contains(t, out, `zenmoney_backup_sync_duration_seconds_count{type="full"} 1`+"\n")
+	assert.Contains(t, out, "zenmoney_backup_export_size_bytes_count 1\n")
+	assert.Contains(t, out, `zenmoney_backup_entities{type="tag"} 1`+"\n")
+	assert.Contains(t, out, "zenmoney_backup_last_success_timestamp_seconds 1.719675e+09\n")
+}
+
+func TestWriteMetrics_profiles(t *testing.T) {
+	home, work := newMetrics(), newMetrics()
+	home.attempt()
+	work.failure(StageSave)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, writeMetrics(&buf, []*Server{{profile: "home", metrics: home}, {profile: "work", metrics: work}}))
+	out := buf.String()
+	assert.Equal(t, 1, strings.Count(out, "# TYPE zenmoney_backup_attempts_total counter\n"))
+	for _, line := range []string{
+		`zenmoney_backup_attempts_total{profile="home"} 1`,
+		`zenmoney_backup_attempts_total{profile="work"} 0`,
+		`zenmoney_backup_failures_total{profile="work",stage="save"} 1`,
+		`zenmoney_backup_sync_duration_seconds_bucket{profile="home",type="full",le="+Inf"} 0`,
+		`zenmoney_backup_export_size_bytes_bucket{profile="work",le="65536"} 0`,
+		`zenmoney_backup_export_size_bytes_count{profile="work"} 0`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+}
@@ -0,0 +1,82 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy sets how failed backups are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // including the first one, no retries if 1 or less
+	Delay       time.Duration // before the first retry, doubled for every next one
+	MaxDelay    time.Duration // upper limit of delay, no limit if zero
+}
+
+// backoff returns delay before retry after attempt failed, attempts are counted from 1.
+// Delay is randomized in [d/2, d) so clients failed together don't retry together.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Delay
+	for i := 1; i < attempt && (p.MaxDelay == 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + rand.N(d-half) // #nosec G404 - jitter doesn't need crypto rand
+}
+
+// SDK error codes are internal, so errors are classified by message, e.g.
+// "SERVER_ERROR: server returned error status: 502".
+var statusRe = regexp.MustCompile(`status: (\d{3})`)
+
+// retryable reports whether backup failed with err can succeed on retry. Network errors,
+// timeouts, 5xx, 429 and 408 responses of the API and storage errors are retryable, invalid token,
+// other 4xx responses and unknown errors are permanent. A run failed after its backup file was saved
+// isn't retried, it would save the backup again.
+func retryable(err error) bool {
+	var se *StageError
+	if !errors.As(err, &se) || se.saved {
+		return false
+	}
+	switch se.Stage {
+	case StageExport:
+		return retryableAPIError(se.Err)
+	case StageSave:
+		return true
+	default:
+		return false
+	}
+}
+
+func retryableAPIError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	msg := err.Error()
+	switch {
+	case strings.HasPrefix(msg, "NETWORK_ERROR"):
+		return true
+	case strings.HasPrefix(msg, "SERVER_ERROR"):
+		m := statusRe.FindStringSubmatch(msg)
+		if m == nil {
+			return false
+		}
+		code, _ := strconv.Atoi(m[1])
+		return code >= 500 || code == 429 || code == 408
+	default:
+		return false
+	}
+}
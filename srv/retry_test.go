@@ -0,0 +1,136 @@
+package srv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, Delay: time.Minute, MaxDelay: 5 * time.Minute}
+	tbl := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 5 * time.Minute},
+		{9, 5 * time.Minute},
+	}
+	for _, tt := range tbl {
+		for range 100 {
+			d := p.backoff(tt.attempt)
+			assert.GreaterOrEqual(t, d, tt.max/2, "attempt %d", tt.attempt)
+			assert.Less(t, d, tt.max, "attempt %d", tt.attempt)
+		}
+	}
+
+	assert.Zero(t, RetryPolicy{}.backoff(1))
+}
+
+func TestRetryable(t *testing.T) {
+	tbl := []struct {
+		err error
+		ok  bool
+	}{
+		{&StageError{Stage: StageExport, Err: errors.New("NETWORK_ERROR: failed to send request after retries: EOF")}, true},
+		{&StageError{Stage: StageExport, Err: errors.New("SERVER_ERROR: server returned error status: 502")}, true},
+		{&StageError{Stage: StageExport, Err: errors.New("SERVER_ERROR: server returned error status: 429")}, true},
+		{&StageError{Stage: StageExport, Err: errors.New("SERVER_ERROR: server returned error status: 408")}, true},
+		{&StageError{Stage: StageExport, Err: errors.New("SERVER_ERROR: server returned error status: 401")}, false},
+		{&StageError{Stage: StageExport, Err: errors.New("SERVER_ERROR: unexpected response")}, false},
+		{&StageError{Stage: StageExport, Err: errors.New("failed to decode sync response")}, false},
+		{&StageError{Stage: StageExport, Err: errors.New("INVALID_TOKEN: token is not provided")}, false},
+		{&StageError{Stage: StageExport, Err: errors.New("INVALID_REQUEST: failed to unmarshal response")}, false},
+		{&StageError{Stage: StageExport, Err: fmt.Errorf("sync: %w", context.DeadlineExceeded)}, true},
+		{&StageError{Stage: StageExport, Err: context.Canceled}, false},
+		{&StageError{Stage: StageSave, Err: errors.New("s3 error 503")}, true},
+		{&StageError{Stage: StageSave, Err: errors.New("s3 error 503"), saved: true}, false},
+		{&StageError{Stage: StageClient, Err: errors.New("INVALID_TOKEN: token is not provided")}, false},
+		{&StageError{Stage: StageEncode, Err: errors.New("json: unsupported value")}, false},
+		{&StageError{Stage: StageRetention, Err: errors.New("can't delete")}, false},
+		{&StageError{Stage: StageMirror, Err: errors.New("database is locked")}, false},
+		{errors.New("unknown"), false},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.ok, retryable(tt.err), "%v", tt.err)
+	}
+}
+
+func TestServer_saveExportRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	retry := RetryPolicy{MaxAttempts: 3, Delay: time.Minute}
+
+	t.Run("success after failures", func(t *testing.T) {
+		clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 10, cancel: cancel}
+		store, ntf := newMemSaver(), &notifierMock{}
+		client := &syncerMock{err: errors.New("SERVER_ERROR: server returned error status: 503"), failures: 2}
+		s := NewServer("test_token", time.Hour, time.Second, store, ntf, WithRetry(retry), WithClock(clock))
+		s.client = client
+
+		assert.NoError(t, s.saveExport(ctx))
+		assert.Equal(t, 3, client.fullCalls)
+		assert.Len(t, clock.waits, 2)
+		assert.Len(t, store.files, 1)
+		assert.False(t, ntf.called, "no notification if a retry succeeded")
+	})
+
+	t.Run("final failure", func(t *testing.T) {
+		clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 10, cancel: cancel}
+		ntf := &notifierMock{}
+		client := &syncerMock{err: errors.New("NETWORK_ERROR: failed to send request after retries: EOF")}
+		s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), ntf, WithRetry(retry), WithClock(clock))
+		s.client = client
+
+		assert.Error(t, s.saveExport(ctx))
+		assert.Equal(t, 3, client.fullCalls)
+		assert.Len(t, clock.waits, 2)
+		assert.Equal(t, "Backup Export Error", ntf.title)
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 10, cancel: cancel}
+		ntf := &notifierMock{}
+		client := &syncerMock{err: errors.New("INVALID_TOKEN: token is not provided")}
+		s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), ntf, WithRetry(retry), WithClock(clock))
+		s.client = client
+
+		assert.Error(t, s.saveExport(ctx))
+		assert.Equal(t, 1, client.fullCalls)
+		assert.Empty(t, clock.waits)
+		assert.True(t, ntf.called)
+	})
+	t.Run("failure after backup is saved", func(t *testing.T) {
+		clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 10, cancel: cancel}
+		store := &failingSaver{memSaver: newMemSaver(), fail: "zen_state.json"}
+		client := &syncerMock{}
+		s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{}, WithRetry(retry), WithClock(clock),
+			WithIncremental(24*time.Hour), WithExporters(exporterMock{}))
+		s.client = client
+
+		err := s.saveExport(ctx)
+		assert.EqualError(t, err, "save: failed to save state: disk full")
+		assert.Equal(t, 1, client.fullCalls, "backup and exports are not saved again")
+		assert.Empty(t, clock.waits)
+		assert.Equal(t, []string{"zen_2024-06-29_15-30-00.json", "zen_2024-06-29_15-30-00.txt"}, store.names())
+	})
+}
+
+// failingSaver is memSaver failing to save file named fail.
+type failingSaver struct {
+	*memSaver
+	fail string
+}
+
+func (f *failingSaver) Save(ctx context.Context, filename string, bs []byte) error {
+	if filename == f.fail {
+		return errors.New("disk full")
+	}
+	return f.memSaver.Save(ctx, filename, bs)
+}
@@ -0,0 +1,153 @@
+package srv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/egregors/zenmoney-backup/notifier"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock moves time forward on every wait, ctx is canceled after limit waits.
+type fakeClock struct {
+	now    time.Time
+	waits  []time.Duration
+	timers []*fakeTimer
+	limit  int
+	cancel context.CancelFunc
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// NewTimer returns timer firing at once, the clock is moved forward by d. Clock cancels context
+// instead of firing the timer after limit of waits.
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	t.Reset(d)
+	return t
+}
+
+// fakeTimer is a timer of fakeClock.
+type fakeTimer struct {
+	clock   *fakeClock
+	ch      chan time.Time
+	stopped bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.waits = append(c.waits, d)
+	t.stopped = false
+	if len(c.waits) > c.limit {
+		c.cancel()
+		return false
+	}
+	c.now = c.now.Add(d)
+	t.ch <- c.now
+	return false
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.stopped = true
+	return false
+}
+
+// dailyAt is a schedule firing every day at hour.
+type dailyAt int
+
+func (h dailyAt) Next(t time.Time) time.Time {
+	next := time.Date(t.Year(), t.Month(), t.Day(), int(h), 0, 0, 0, t.Location())
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func TestEvery(t *testing.T) {
+	now := time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, now.Add(time.Hour), Every(time.Hour).Next(now))
+}
+
+func TestServer_RunSchedule(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 2, cancel: cancel}
+
+	store, client := newMemSaver(), &syncerMock{}
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{}, WithSchedule(dailyAt(3)), WithClock(clock))
+	s.client = client
+	s.Run(ctx)
+
+	assert.Equal(t, 3, client.fullCalls, "backup on start and two scheduled ones")
+	assert.Equal(t, []time.Duration{11*time.Hour + 30*time.Minute, 24 * time.Hour, 24 * time.Hour}, clock.waits)
+	if assert.Len(t, clock.timers, 1, "the timer is reused") {
+		assert.True(t, clock.timers[0].stopped)
+	}
+	assert.Equal(t, []string{
+		"zen_2024-06-29_15-30-00.json",
+		"zen_2024-06-30_03-00-00.json",
+		"zen_2024-07-01_03-00-00.json",
+	}, store.names())
+}
+
+func TestServer_RunDefaultSchedule(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &fakeClock{now: time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC), limit: 1, cancel: cancel}
+
+	s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{}, WithClock(clock))
+	s.client = &syncerMock{}
+	s.Run(ctx)
+
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour}, clock.waits)
+}
+
+// chanNotifier sends results of backups to the channel.
+type chanNotifier struct {
+	notifierMock
+	results chan notifier.Result
+}
+
+func (n *chanNotifier) NotifyResult(r notifier.Result) error {
+	n.results <- r
+	return nil
+}
+
+func TestServer_Trigger(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ntf := &chanNotifier{results: make(chan notifier.Result, 10)}
+	s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), ntf)
+	s.client = &syncerMock{}
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	<-ntf.results // backup on start
+	assert.True(t, s.Trigger())
+	select {
+	case <-ntf.results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("triggered backup is not made")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestServer_TriggerPending(t *testing.T) {
+	s := NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{})
+	assert.True(t, s.Trigger())
+	assert.False(t, s.Trigger(), "one pending backup is enough")
+}
@@ -4,50 +4,288 @@ package srv
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/egregors/zenmoney-backup/codec"
+	"github.com/egregors/zenmoney-backup/export"
+	"github.com/egregors/zenmoney-backup/notifier"
+	"github.com/egregors/zenmoney-backup/retention"
+	"github.com/egregors/zenmoney-backup/snapshot"
 	log "github.com/go-pkgz/lgr"
 	"github.com/nemirlev/zenmoney-go-sdk/v2/api"
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
 )
 
 // Saver is interface for file storage.
 type Saver interface {
-	Save(filename string, bs []byte) error
+	Save(ctx context.Context, filename string, bs []byte) error
+	Load(ctx context.Context, filename string) ([]byte, error)
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, filename string) error
 }
 
-// Notifier is an interface for sending notifications.
+// StreamSaver is an optional Saver extension, it saves file read from r as it's encoded, so backup
+// isn't held in memory as a whole.
+type StreamSaver interface {
+	SaveStream(ctx context.Context, filename string, r io.Reader) error
+}
+
+// Notifier is an interface for sending notifications. Notifier implementing notifier.ResultNotifier
+// also gets the outcome of every successful run.
 type Notifier interface {
 	Notify(title, message string) error
 }
 
+// Exporter converts backup to another format, see WithExporters.
+type Exporter interface {
+	Ext() string
+	Export(w io.Writer, resp models.Response) error
+}
+
+// SplitExporter is Exporter saving a file per part of backup, e.g. a statement per account, named
+// like zen_2024-06-29_15-30-45.Card.ofx.
+type SplitExporter interface {
+	Exporter
+	Parts(resp models.Response) []export.Part
+}
+
+// Mirror keeps a copy of ZenMoney data in sync with backups, e.g. SQLite database, see WithMirror.
+type Mirror interface {
+	Apply(ctx context.Context, resp models.Response, full bool) error
+}
+
+// syncer is a part of ZenMoney API client used by Server.
+type syncer interface {
+	FullSync(ctx context.Context) (models.Response, error)
+	SyncSince(ctx context.Context, lastSync time.Time) (models.Response, error)
+}
+
 // Server is backup server.
 type Server struct {
 	token     string
 	sleepTime time.Duration
 	timeout   time.Duration
 	store     Saver
-	client    *api.Client
+	client    syncer
 	notifier  Notifier
+
+	incremental bool
+	fullEvery   time.Duration
+
+	retention retention.Policy
+	dryRun    bool
+
+	layout    codec.Encoder
+	encoders  []codec.Encoder
+	exporters []Exporter
+	mirror    Mirror
+
+	dedup bool
+
+	schedule Schedule
+	clock    Clock
+
+	retry RetryPolicy
+
+	statusMu sync.Mutex
+	status   Status
+	metrics  *metrics
+
+	trigger chan struct{} // manual backup requests, handled by Run
+
+	profile string
+}
+
+// Option is a functional option for Server.
+type Option func(srv *Server)
+
+// WithIncremental enables incremental backups: only changes since the previous run
+// are downloaded and saved as delta files, a full backup is made every fullEvery.
+func WithIncremental(fullEvery time.Duration) Option {
+	return func(srv *Server) {
+		srv.incremental = true
+		srv.fullEvery = fullEvery
+	}
+}
+
+// WithRetention enables pruning of old backups after every successful backup.
+// In dry run mode backups to delete are only logged.
+func WithRetention(p retention.Policy, dryRun bool) Option {
+	return func(srv *Server) {
+		srv.retention = p
+		srv.dryRun = dryRun
+	}
+}
+
+// WithEncoders sets pipeline stages (e.g. encryption) applied to backups before they are saved.
+func WithEncoders(encoders ...codec.Encoder) Option {
+	return func(srv *Server) {
+		srv.encoders = encoders
+	}
+}
+
+// WithLayout sets layout stage applied to backups before encoders, e.g. codec.Split saving a file per
+// entity type. Exports are not affected.
+func WithLayout(layout codec.Encoder) Option {
+	return func(srv *Server) {
+		srv.layout = layout
+	}
+}
+
+// WithExporters enables exports of full backups to other formats, e.g. CSV. Exports are saved next
+// to the backup with the same name and extension of the format, e.g. zen_2024-06-29_15-30-45.csv
+// (a file per part for SplitExporter), encoded by the same pipeline stages, and deleted together with
+// the backup by retention.
+func WithExporters(exporters ...Exporter) Option {
+	return func(srv *Server) {
+		srv.exporters = exporters
+	}
+}
+
+// WithMirror enables updates of m by data of every backup, full or delta. The mirror is updated
+// before the checkpoint moves forward, so changes it missed because of an error are downloaded again
+// by the next run.
+func WithMirror(m Mirror) Option {
+	return func(srv *Server) {
+		srv.mirror = m
+	}
+}
+
+// WithDedup enables skipping of backups identical to the previous one. In incremental mode
+// empty deltas are skipped, but the checkpoint still moves forward.
+func WithDedup() Option {
+	return func(srv *Server) {
+		srv.dedup = true
+	}
+}
+
+// WithSchedule sets schedule of backups, by default a backup is made every sleepTime.
+func WithSchedule(s Schedule) Option {
+	return func(srv *Server) {
+		srv.schedule = s
+	}
+}
+
+// WithRetry enables retries of failed backups, see RetryPolicy.
+func WithRetry(p RetryPolicy) Option {
+	return func(srv *Server) {
+		srv.retry = p
+	}
+}
+
+// WithProfile sets name of the profile, it's added to names of backup files, logs and notifications
+// so several servers can share one storage.
+func WithProfile(name string) Option {
+	return func(srv *Server) {
+		srv.profile = name
+	}
+}
+
+// WithClock sets source of time, for tests.
+func WithClock(c Clock) Option {
+	return func(srv *Server) {
+		srv.clock = c
+	}
 }
 
 // NewServer makes Server from options.
-func NewServer(token string, sleepTime time.Duration, timeout time.Duration, storage Saver, notifier Notifier) *Server {
-	return &Server{
+func NewServer(token string, sleepTime time.Duration, timeout time.Duration, storage Saver, notifier Notifier, opts ...Option) *Server {
+	srv := &Server{
 		token:     token,
 		sleepTime: sleepTime,
 		timeout:   timeout,
 		store:     storage,
 		notifier:  notifier,
+		schedule:  Every(sleepTime),
+		clock:     realClock{},
+		metrics:   newMetrics(),
+		trigger:   make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(srv)
 	}
+	return srv
+}
+
+// Profile returns name of the profile, empty if it's not set.
+func (srv *Server) Profile() string {
+	return srv.profile
 }
 
-// Run starts Server.
+// Run starts Server, a backup is made right away and then by schedule until ctx is canceled.
+// Backups requested by Trigger are made between scheduled ones.
 func (srv *Server) Run(ctx context.Context) {
-	log.Printf("[INFO] login...")
+	if err := srv.login(); err != nil {
+		return
+	}
+
+	_ = srv.saveExport(ctx)
+
+	next := srv.schedule.Next(srv.clock.Now())
+	// the same timer keeps running through manual backups, it's reset only when it fires
+	timer := srv.clock.NewTimer(next.Sub(srv.clock.Now()))
+	defer timer.Stop()
+	for {
+		srv.logf("[INFO] next backup at %s", next.Format(time.RFC3339))
+		srv.updateStatus(func(st *Status) { st.NextRun = next })
+		select {
+		case <-ctx.Done():
+			return
+		case <-srv.trigger:
+			srv.logf("[INFO] manual backup is triggered")
+			_ = srv.saveExport(ctx)
+		case <-timer.C():
+			_ = srv.saveExport(ctx)
+			next = srv.schedule.Next(srv.clock.Now())
+			timer.Reset(next.Sub(srv.clock.Now()))
+		}
+	}
+}
+
+// Trigger requests a backup out of schedule. It is made by Run in the same loop as scheduled
+// backups, so they never overlap. False is returned if a requested backup is already pending.
+func (srv *Server) Trigger() bool {
+	select {
+	case srv.trigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// RunOnce makes a single backup and applies retention policy. Returned error is *StageError.
+func (srv *Server) RunOnce(ctx context.Context) error {
+	if err := srv.login(); err != nil {
+		return err
+	}
+	return srv.saveExport(ctx)
+}
 
+// login creates API client, unless it is set already.
+func (srv *Server) login() error {
+	if srv.client == nil {
+		srv.logf("[INFO] login...")
+		client, err := srv.newClient()
+		if err != nil {
+			srv.logf("[ERROR] failed to create client: %s", err)
+			srv.metrics.failure(StageClient)
+			srv.sendNotification(stageTitles[StageClient], err.Error())
+			return &StageError{Stage: StageClient, Err: err}
+		}
+		srv.client = client
+	}
+	srv.updateStatus(func(st *Status) { st.ClientReady = true })
+	return nil
+}
+
+func (srv *Server) newClient() (*api.Client, error) {
 	// Configure HTTP transport with proper timeouts to avoid TLS handshake timeout issues
 	transport := &http.Transport{
 		DialContext: (&net.Dialer{
@@ -66,92 +304,416 @@ func (srv *Server) Run(ctx context.Context) {
 		Timeout:   srv.timeout,
 	}
 
-	log.Printf("[DEBUG] creating API client with timeout=%s, TLS handshake timeout=30s", srv.timeout)
+	srv.logf("[DEBUG] creating API client with timeout=%s, TLS handshake timeout=30s", srv.timeout)
 
 	// Note: We pass both WithHTTPClient and WithTimeout because:
 	// - httpClient.Timeout is the effective timeout enforced by Go's http package
 	// - WithTimeout passes the timeout to the SDK for internal configuration
-	client, err := api.NewClient(
+	return api.NewClient(
 		srv.token,
 		api.WithHTTPClient(httpClient),
 		api.WithTimeout(srv.timeout),
 	)
+}
+
+// saveExport makes a backup, retrying it according to retry policy. Errors are logged,
+// notification is sent only if the last attempt fails.
+func (srv *Server) saveExport(ctx context.Context) error {
+	srv.updateStatus(func(st *Status) { st.Running = true })
+	defer srv.updateStatus(func(st *Status) { st.Running = false })
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		srv.metrics.attempt()
+		err = srv.backup(ctx)
+		var se *StageError
+		if errors.As(err, &se) {
+			srv.metrics.failure(se.Stage)
+		}
+		if err == nil || attempt >= srv.retry.MaxAttempts || !retryable(err) {
+			break
+		}
+		delay := srv.retry.backoff(attempt)
+		srv.logf("[WARN] attempt %d of %d failed: %s, retry in %s", attempt, srv.retry.MaxAttempts, err, delay)
+		if !srv.sleep(ctx, delay) {
+			break
+		}
+	}
+
+	var se *StageError
+	if errors.As(err, &se) {
+		srv.logf("[ERROR] %s failed: %s", se.Stage, se.Err)
+		srv.sendNotification(stageTitles[se.Stage], se.Err.Error())
+	}
 	if err != nil {
-		log.Printf("[ERROR] failed to create client: %s", err)
-		srv.sendNotification("Client Creation Error", err.Error())
-		return
+		now := srv.clock.Now()
+		srv.updateStatus(func(st *Status) { st.LastFailure, st.LastError = now, err.Error() })
 	}
-	srv.client = client
+	return err
+}
 
-	srv.saveExport(ctx)
+// backup downloads data, saves it and prunes old backups. Returned error is *StageError.
+func (srv *Server) backup(ctx context.Context) (err error) {
+	now := srv.clock.Now()
+	saved := false
+	defer func() {
+		var se *StageError
+		if saved && errors.As(err, &se) {
+			se.saved = true
+		}
+	}()
 
-	ticker := time.NewTicker(srv.sleepTime)
-	for {
-		select {
-		case <-ctx.Done():
-			ticker.Stop()
-			return
-		case <-ticker.C:
-			srv.saveExport(ctx)
+	st, full := srv.nextSync(ctx, now)
+	since := 0
+	if full {
+		srv.logf("[INFO] downloading...")
+	} else {
+		since = st.ServerTimestamp
+		srv.logf("[INFO] downloading changes since %s...", time.Unix(int64(since), 0).Format(time.RFC3339))
+	}
+
+	resp, err := srv.export(ctx, since)
+	if err != nil {
+		return &StageError{Stage: StageExport, Err: err}
+	}
+
+	if full {
+		srv.metrics.setEntities(resp)
+	}
+
+	hash := ""
+	if srv.dedup {
+		if hash, err = snapshot.Hash(resp); err != nil {
+			return &StageError{Stage: StageEncode, Err: fmt.Errorf("failed to marshal data: %w", err)}
+		}
+	}
+
+	if srv.dedup && srv.unchanged(st, resp, hash, full) {
+		srv.logf("[INFO] nothing changed since %s (sha256 %s), backup skipped", st.Last, hash)
+		if err := srv.applyMirror(ctx, resp, full); err != nil {
+			return err
+		}
+		if srv.incremental {
+			st.ServerTimestamp = resp.ServerTimestamp
+		}
+		if full {
+			// the identical base is still a valid start of the chain, so the next full backup is due later
+			st.BaseTime = now
+		}
+		if err := srv.saveState(ctx, st); err != nil {
+			return &StageError{Stage: StageSave, Err: fmt.Errorf("failed to save state: %w", err)}
+		}
+		srv.report(notifier.Result{Profile: srv.profile, Time: now, File: st.Last, Hash: hash, Delta: !full, Skipped: true})
+		return nil
+	}
+
+	encoders := srv.encoders
+	if srv.layout != nil {
+		encoders = append([]codec.Encoder{srv.layout}, srv.encoders...)
+	}
+	fileName := srv.genFileName(now)
+	if !full {
+		fileName = srv.genDeltaFileName(now)
+	}
+	fileName += codec.Ext(encoders...)
+	size, err := srv.saveEncoded(ctx, fileName, "marshal data", func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(resp)
+	}, encoders)
+	if err != nil {
+		return err
+	}
+	saved = true
+	srv.metrics.observeSize(size)
+	if hash != "" {
+		srv.logf("[INFO] %s saved (sha256 %s)", fileName, hash)
+	} else {
+		srv.logf("[INFO] %s saved", fileName)
+	}
+	if full {
+		if err := srv.saveExports(ctx, now, resp); err != nil {
+			return err
+		}
+	}
+	if err := srv.applyMirror(ctx, resp, full); err != nil {
+		return err
+	}
+
+	if srv.incremental || srv.dedup {
+		st.ServerTimestamp = resp.ServerTimestamp
+		st.Last = fileName
+		if full {
+			st.Base, st.BaseTime, st.Hash = fileName, now, hash
+		}
+		if err := srv.saveState(ctx, st); err != nil {
+			return &StageError{Stage: StageSave, Err: fmt.Errorf("failed to save state: %w", err)}
+		}
+	}
+	srv.report(notifier.Result{Profile: srv.profile, Time: now, File: fileName, Size: size, Hash: hash, Delta: !full})
+
+	if srv.retention.Enabled() {
+		if _, err := srv.Prune(ctx, now, srv.dryRun); err != nil {
+			return &StageError{Stage: StageRetention, Err: err}
+		}
+	}
+	return nil
+}
+
+// saveExports saves resp in formats of exporters. Returned error is *StageError.
+func (srv *Server) saveExports(ctx context.Context, now time.Time, resp models.Response) error {
+	stem := strings.TrimSuffix(srv.genFileName(now), ".json")
+	for _, e := range srv.exporters {
+		parts := []export.Part{{Export: func(w io.Writer) error { return e.Export(w, resp) }}}
+		if se, ok := e.(SplitExporter); ok {
+			parts = se.Parts(resp)
+		}
+		for _, p := range parts {
+			fileName := stem
+			if p.Name != "" {
+				fileName += "." + p.Name
+			}
+			fileName += e.Ext() + codec.Ext(srv.encoders...)
+			if _, err := srv.saveEncoded(ctx, fileName, "export "+e.Ext(), p.Export, srv.encoders); err != nil {
+				return err
+			}
+			srv.logf("[INFO] %s saved", fileName)
 		}
 	}
+	return nil
 }
 
-func (srv *Server) saveExport(ctx context.Context) {
-	log.Printf("[INFO] downloading...")
-	bs, err := srv.export(ctx)
+// saveEncoded saves content written by write through encoders to fileName and returns size of the file.
+// Content is streamed to store if it's StreamSaver, otherwise the whole file is encoded to memory first.
+// Encoding errors are described as failed to do what. Returned error is *StageError.
+func (srv *Server) saveEncoded(ctx context.Context, fileName, what string, write func(w io.Writer) error,
+	encoders []codec.Encoder) (int, error) {
+	ss, ok := srv.store.(StreamSaver)
+	if !ok {
+		bs, err := codec.EncodeFunc(write, encoders...)
+		if err != nil {
+			return 0, &StageError{Stage: StageEncode, Err: fmt.Errorf("failed to %s: %w", what, err)}
+		}
+		if err := srv.store.Save(ctx, fileName, bs); err != nil {
+			return 0, &StageError{Stage: StageSave, Err: err}
+		}
+		return len(bs), nil
+	}
+
+	pr, pw := io.Pipe()
+	encoded := make(chan error, 1)
+	go func() {
+		err := codec.EncodeTo(pw, write, encoders...)
+		_ = pw.CloseWithError(err) // saver gets EOF if err is nil
+		encoded <- err
+	}()
+	r := &countingReader{r: pr}
+	err := ss.SaveStream(ctx, fileName, r)
+	_ = pr.Close() // unblocks encoding if saver stopped reading
+	// encoding fails with io.ErrClosedPipe only if saver has failed
+	if encErr := <-encoded; encErr != nil && !errors.Is(encErr, io.ErrClosedPipe) {
+		return 0, &StageError{Stage: StageEncode, Err: fmt.Errorf("failed to %s: %w", what, encErr)}
+	}
 	if err != nil {
-		log.Printf("[ERROR] failed: %s", err)
-		srv.sendNotification("Backup Export Error", err.Error())
-		return
+		return 0, &StageError{Stage: StageSave, Err: err}
+	}
+	return r.n, nil
+}
+
+// countingReader counts bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// applyMirror updates mirror by resp, if it's set. Returned error is *StageError.
+func (srv *Server) applyMirror(ctx context.Context, resp models.Response, full bool) error {
+	if srv.mirror == nil {
+		return nil
+	}
+	if err := srv.mirror.Apply(ctx, resp, full); err != nil {
+		return &StageError{Stage: StageMirror, Err: err}
+	}
+	srv.logf("[DEBUG] mirror updated")
+	return nil
+}
+
+// sleep waits for d, false is returned if ctx is canceled earlier.
+func (srv *Server) sleep(ctx context.Context, d time.Duration) bool {
+	timer := srv.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+// unchanged reports whether resp is the same as the previous backup. A full backup is compared
+// with the hash of the previous full one, a delta is unchanged if it is empty.
+func (srv *Server) unchanged(st state, resp models.Response, hash string, full bool) bool {
+	if !full {
+		return snapshot.IsEmpty(resp)
 	}
+	return st.Hash != "" && st.Hash == hash
+}
 
-	fileName := srv.genFileName(time.Now())
-	err = srv.store.Save(fileName, bs)
+// Prune deletes backups not matching retention policy and returns their names.
+// In dry run mode nothing is deleted.
+func (srv *Server) Prune(ctx context.Context, now time.Time, dryRun bool) ([]string, error) {
+	names, err := srv.store.List(ctx)
 	if err != nil {
-		log.Printf("[ERROR] downloading failed: %s", err)
-		srv.sendNotification("Backup Save Error", err.Error())
-		return
+		return nil, err
+	}
+
+	del := srv.retention.Plan(snapshot.FilterProfile(names, srv.profile), now)
+	del = append(del, exportsOf(names, del)...)
+	if dryRun {
+		for _, name := range del {
+			srv.logf("[INFO] dry run, %s would be deleted", name)
+		}
+		return del, nil
+	}
+
+	var errs []error
+	deleted := make([]string, 0, len(del))
+	for _, name := range del {
+		if err := srv.store.Delete(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("can't delete %s: %w", name, err))
+			continue
+		}
+		deleted = append(deleted, name)
+		srv.logf("[DEBUG] %s deleted", name)
+	}
+	if len(deleted) > 0 {
+		srv.logf("[INFO] %d old backups deleted", len(deleted))
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// exportsOf returns exports of backups from names, see WithExporters. Export and its backup have
+// the same name up to the first dot, e.g. zen_2024-06-29_15-30-45.csv.age and zen_2024-06-29_15-30-45.json.age.
+func exportsOf(names, backups []string) []string {
+	stems := make(map[string]bool, len(backups))
+	for _, name := range backups {
+		stem, _, _ := strings.Cut(name, ".")
+		stems[stem] = true
+	}
+	var res []string
+	for _, name := range names {
+		stem, _, _ := strings.Cut(name, ".")
+		if _, isBackup := snapshot.ParseFileName(name); !isBackup && stems[stem] {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// nextSync returns state of the previous run and whether full backup is required.
+func (srv *Server) nextSync(ctx context.Context, now time.Time) (st state, full bool) {
+	if !srv.incremental && !srv.dedup {
+		return state{}, true
+	}
+
+	st, err := srv.loadState(ctx)
+	if err != nil {
+		srv.logf("[WARN] can't load state, full backup will be made: %s", err)
+		return state{}, true
+	}
+	if !srv.incremental || st.ServerTimestamp == 0 || st.Base == "" {
+		return st, true
 	}
-	log.Printf("[INFO] %s saved", fileName)
-	log.Printf("[INFO] sleep for %s", srv.sleepTime.String())
+	if now.Sub(st.BaseTime) >= srv.fullEvery {
+		srv.logf("[INFO] last full backup %s is older than %s", st.Base, srv.fullEvery)
+		return st, true
+	}
+	return st, false
 }
 
-func (srv *Server) export(ctx context.Context) ([]byte, error) {
-	log.Printf("[DEBUG] downloading data with timeout=%s ...", srv.timeout)
+// export downloads all data, or only changes since serverTimestamp if it isn't zero.
+func (srv *Server) export(ctx context.Context, serverTimestamp int) (models.Response, error) {
+	srv.logf("[DEBUG] downloading data with timeout=%s ...", srv.timeout)
 	startTime := time.Now()
 	ctx, cancel := context.WithTimeout(ctx, srv.timeout)
 	defer cancel()
 
-	resp, err := srv.client.FullSync(ctx)
+	var resp models.Response
+	var err error
+	if serverTimestamp == 0 {
+		resp, err = srv.client.FullSync(ctx)
+	} else {
+		resp, err = srv.client.SyncSince(ctx, time.Unix(int64(serverTimestamp), 0))
+	}
 	if err != nil {
 		elapsed := time.Since(startTime)
-		log.Printf("[ERROR] failed to download data after %s: %s", elapsed, err)
-		return nil, err
+		srv.logf("[ERROR] failed to download data after %s: %s", elapsed, err)
+		return models.Response{}, err
 	}
-	_ = resp
 
 	elapsed := time.Since(startTime)
-	log.Printf("[DEBUG] API request completed in %s", elapsed)
-
-	bs, err := json.Marshal(resp)
-	if err != nil {
-		log.Printf("[ERROR] failed to marshal data: %s", err)
-		return nil, err
-	}
-
-	log.Printf("[DEBUG] downloaded")
-	return bs, nil
+	srv.metrics.observeSync(serverTimestamp == 0, elapsed)
+	srv.logf("[DEBUG] API request completed in %s", elapsed)
+	srv.logf("[DEBUG] downloaded")
+	return resp, nil
 }
 
 func (srv *Server) sendNotification(title, message string) {
+	title = withProfile(srv.profile, title)
 	if srv.notifier != nil {
 		if err := srv.notifier.Notify(title, message); err != nil {
-			log.Printf("[WARN] failed to send notification: %s", err)
+			srv.metrics.failure(StageNotify)
+			srv.logf("[WARN] failed to send notification: %s", err)
+		}
+	}
+}
+
+// report updates status with result of successful backup and sends it to notifier.
+func (srv *Server) report(r notifier.Result) {
+	srv.metrics.success(r.Time, r.Skipped)
+	srv.updateStatus(func(st *Status) {
+		st.LastSuccess, st.LastFile = r.Time, r.File
+		if !r.Skipped {
+			st.LastSize = r.Size
 		}
+	})
+
+	rn, ok := srv.notifier.(notifier.ResultNotifier)
+	if !ok {
+		return
+	}
+	if err := rn.NotifyResult(r); err != nil {
+		srv.metrics.failure(StageNotify)
+		srv.logf("[WARN] failed to send notification: %s", err)
 	}
 }
 
 func (srv *Server) genFileName(t time.Time) string {
-	return fmt.Sprintf("zen_%s.json", t.Format("2006-01-02_15-04-05"))
+	return fmt.Sprintf("%s%s.json", srv.filePrefix(), t.Format("2006-01-02_15-04-05"))
+}
+
+func (srv *Server) genDeltaFileName(t time.Time) string {
+	return fmt.Sprintf("%s%s.delta.json", srv.filePrefix(), t.Format("2006-01-02_15-04-05"))
+}
+
+// filePrefix returns prefix of files of the server, "zen_" or "zen_<profile>_".
+func (srv *Server) filePrefix() string {
+	if srv.profile == "" {
+		return "zen_"
+	}
+	return "zen_" + srv.profile + "_"
+}
+
+// logf logs message with profile name after the level, e.g. "[INFO] household: saved".
+func (srv *Server) logf(format string, args ...any) {
+	if srv.profile != "" {
+		if i := strings.Index(format, "] "); strings.HasPrefix(format, "[") && i > 0 {
+			format = format[:i+2] + srv.profile + ": " + format[i+2:]
+		}
+	}
+	log.Printf(format, args...)
 }
@@ -1,18 +1,139 @@
 package srv
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"filippo.io/age"
+	"github.com/egregors/zenmoney-backup/codec"
+	"github.com/egregors/zenmoney-backup/export"
+	"github.com/egregors/zenmoney-backup/notifier"
+	"github.com/egregors/zenmoney-backup/retention"
+	"github.com/nemirlev/zenmoney-go-sdk/v2/models"
 	"github.com/stretchr/testify/assert"
 )
 
 type saverMock struct{}
 
-func (s saverMock) Save(_ string, _ []byte) error {
+func (s saverMock) Save(_ context.Context, _ string, _ []byte) error {
 	return nil
 }
 
+func (s saverMock) Load(_ context.Context, _ string) ([]byte, error) {
+	return nil, fs.ErrNotExist
+}
+
+func (s saverMock) List(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (s saverMock) Delete(_ context.Context, _ string) error {
+	return nil
+}
+
+// memSaver keeps saved files in memory.
+type memSaver struct {
+	files   map[string][]byte
+	saveErr error
+}
+
+func newMemSaver() *memSaver {
+	return &memSaver{files: map[string][]byte{}}
+}
+
+func (m *memSaver) Save(_ context.Context, filename string, bs []byte) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.files[filename] = bs
+	return nil
+}
+
+func (m *memSaver) Load(_ context.Context, filename string) ([]byte, error) {
+	bs, ok := m.files[filename]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return bs, nil
+}
+
+func (m *memSaver) List(_ context.Context) ([]string, error) {
+	return m.names(), nil
+}
+
+func (m *memSaver) Delete(_ context.Context, filename string) error {
+	if _, ok := m.files[filename]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(m.files, filename)
+	return nil
+}
+
+// streamSaver is memSaver saving streams, it fails without reading if err is set.
+type streamSaver struct {
+	*memSaver
+	streamed []string
+	err      error
+}
+
+func (s *streamSaver) SaveStream(_ context.Context, filename string, r io.Reader) error {
+	if s.err != nil {
+		return s.err
+	}
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.streamed = append(s.streamed, filename)
+	s.files[filename] = bs
+	return nil
+}
+
+func (m *memSaver) names() []string {
+	res := make([]string, 0, len(m.files))
+	for k := range m.files {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// syncerMock counts calls and returns next server timestamp on every sync.
+type syncerMock struct {
+	ts        int
+	fullCalls int
+	since     []time.Time
+	noChanges bool  // SyncSince returns empty delta
+	err       error // returned by the first failures calls, or by all calls if failures is zero
+	failures  int
+}
+
+func (c *syncerMock) FullSync(_ context.Context) (models.Response, error) {
+	c.fullCalls++
+	if c.err != nil && (c.failures == 0 || c.fullCalls <= c.failures) {
+		return models.Response{}, c.err
+	}
+	c.ts += 100
+	return models.Response{ServerTimestamp: c.ts, Tag: []models.Tag{{ID: "full"}}}, nil
+}
+
+func (c *syncerMock) SyncSince(_ context.Context, lastSync time.Time) (models.Response, error) {
+	c.since = append(c.since, lastSync)
+	c.ts += 100
+	if c.noChanges {
+		return models.Response{ServerTimestamp: c.ts}, nil
+	}
+	return models.Response{ServerTimestamp: c.ts, Deletion: []models.Deletion{{ID: "gone", Object: "tag"}}}, nil
+}
+
 type notifierMock struct {
 	called bool
 	title  string
@@ -26,6 +147,16 @@ func (n *notifierMock) Notify(title, message string) error {
 	return nil
 }
 
+type resultNotifierMock struct {
+	notifierMock
+	results []notifier.Result
+}
+
+func (n *resultNotifierMock) NotifyResult(r notifier.Result) error {
+	n.results = append(n.results, r)
+	return nil
+}
+
 func TestNewServer(t *testing.T) {
 	token := "test_token"
 	dur := time.Minute * 30
@@ -68,3 +199,417 @@ func TestServer_genFileName(t *testing.T) {
 		s.genFileName(bT),
 	)
 }
+
+func TestServer_genDeltaFileName(t *testing.T) {
+	s := Server{}
+	bT, _ := time.Parse("2006-01-02_15-04-05", "2022-03-12_21-48-00")
+	assert.Equal(t, "zen_2022-03-12_21-48-00.delta.json", s.genDeltaFileName(bT))
+}
+
+func TestServer_saveExport(t *testing.T) {
+	store, client := newMemSaver(), &syncerMock{}
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{})
+	s.client = client
+
+	s.saveExport(context.Background())
+	assert.Equal(t, 1, client.fullCalls)
+	assert.Len(t, store.names(), 1)
+	assert.True(t, strings.HasSuffix(store.names()[0], ".json"))
+	assert.NotContains(t, store.files, "zen_state.json", "state is kept only in incremental mode")
+}
+
+func TestServer_saveExportIncremental(t *testing.T) {
+	store, client := newMemSaver(), &syncerMock{}
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{}, WithIncremental(24*time.Hour))
+	s.client = client
+
+	// no state, full backup
+	s.saveExport(context.Background())
+	assert.Equal(t, 1, client.fullCalls)
+	st, err := s.loadState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 100, st.ServerTimestamp)
+	assert.Contains(t, store.files, st.Base)
+
+	// delta since the last server timestamp
+	s.saveExport(context.Background())
+	assert.Equal(t, 1, client.fullCalls)
+	assert.Equal(t, []time.Time{time.Unix(100, 0)}, client.since)
+	st2, err := s.loadState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 200, st2.ServerTimestamp)
+	assert.Equal(t, st.Base, st2.Base)
+
+	var deltas []string
+	for _, name := range store.names() {
+		if strings.HasSuffix(name, ".delta.json") {
+			deltas = append(deltas, name)
+		}
+	}
+	assert.Len(t, deltas, 1)
+	var delta models.Response
+	assert.NoError(t, json.Unmarshal(store.files[deltas[0]], &delta))
+	assert.Equal(t, []models.Deletion{{ID: "gone", Object: "tag"}}, delta.Deletion)
+
+	// base is too old, full backup again
+	st2.BaseTime = st2.BaseTime.Add(-25 * time.Hour)
+	assert.NoError(t, s.saveState(context.Background(), st2))
+	s.saveExport(context.Background())
+	assert.Equal(t, 2, client.fullCalls)
+	st3, err := s.loadState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 300, st3.ServerTimestamp)
+}
+
+func TestServer_saveExportDedup(t *testing.T) {
+	store, client, ntf := newMemSaver(), &syncerMock{}, &resultNotifierMock{}
+	s := NewServer("test_token", time.Hour, time.Second, store, ntf, WithDedup())
+	s.client = client
+
+	s.saveExport(context.Background())
+	assert.Len(t, store.names(), 2, "backup and state")
+	st, err := s.loadState(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, st.Hash, 64)
+	assert.Contains(t, store.files, st.Last)
+
+	// the same data, only server timestamp is changed
+	s.saveExport(context.Background())
+	assert.Equal(t, 2, client.fullCalls)
+	assert.Len(t, store.names(), 2, "duplicate is skipped")
+
+	assert.Len(t, ntf.results, 2)
+	assert.Equal(t, notifier.Result{Time: ntf.results[0].Time, File: st.Last, Size: len(store.files[st.Last]), Hash: st.Hash}, ntf.results[0])
+	assert.True(t, ntf.results[1].Skipped)
+	assert.Equal(t, st.Last, ntf.results[1].File)
+	assert.Equal(t, st.Hash, ntf.results[1].Hash)
+	assert.False(t, ntf.called)
+
+	// data is changed
+	st.Hash = "old"
+	assert.NoError(t, s.saveState(context.Background(), st))
+	s.saveExport(context.Background())
+	assert.Len(t, ntf.results, 3)
+	assert.False(t, ntf.results[2].Skipped)
+}
+
+func TestServer_saveExportDedupIncremental(t *testing.T) {
+	store, client, ntf := newMemSaver(), &syncerMock{noChanges: true}, &resultNotifierMock{}
+	s := NewServer("test_token", time.Hour, time.Second, store, ntf, WithIncremental(24*time.Hour), WithDedup())
+	s.client = client
+
+	s.saveExport(context.Background())
+	s.saveExport(context.Background())
+	assert.Len(t, store.names(), 2, "full backup and state, empty delta is skipped")
+	st, err := s.loadState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 200, st.ServerTimestamp, "checkpoint is moved forward")
+	assert.Equal(t, st.Base, st.Last)
+
+	assert.Len(t, ntf.results, 2)
+	assert.True(t, ntf.results[1].Delta)
+	assert.True(t, ntf.results[1].Skipped)
+
+	// the same data when full backup is due
+	st.BaseTime = st.BaseTime.Add(-25 * time.Hour)
+	assert.NoError(t, s.saveState(context.Background(), st))
+	s.saveExport(context.Background())
+	assert.Equal(t, 2, client.fullCalls)
+	assert.Len(t, store.names(), 2)
+	assert.True(t, ntf.results[2].Skipped)
+	assert.False(t, ntf.results[2].Delta)
+
+	// the skipped full backup restarts the interval, so the next run is incremental
+	s.saveExport(context.Background())
+	assert.Equal(t, 2, client.fullCalls)
+	assert.Len(t, client.since, 2)
+	assert.True(t, ntf.results[3].Delta)
+}
+
+func TestServer_RunOnce(t *testing.T) {
+	store, client, ntf := newMemSaver(), &syncerMock{}, &notifierMock{}
+	s := NewServer("test_token", time.Hour, time.Second, store, ntf, WithRetention(retention.Policy{KeepLast: 1}, false))
+	s.client = client
+	assert.NoError(t, store.Save(context.Background(), "zen_2022-03-12_21-48-00.json", []byte("{}")))
+
+	assert.NoError(t, s.RunOnce(context.Background()))
+	assert.Equal(t, 1, client.fullCalls)
+	assert.Len(t, store.files, 1)
+	assert.NotContains(t, store.files, "zen_2022-03-12_21-48-00.json", "retention is applied")
+	assert.False(t, ntf.called)
+}
+
+func TestServer_RunOnceErrors(t *testing.T) {
+	tbl := []struct {
+		name   string
+		client *syncerMock
+		store  *memSaver
+		stage  Stage
+		title  string
+	}{
+		{"export", &syncerMock{err: errors.New("INVALID_TOKEN")}, newMemSaver(), StageExport, "Backup Export Error"},
+		{"save", &syncerMock{}, &memSaver{saveErr: errors.New("disk full")}, StageSave, "Backup Save Error"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			ntf := &notifierMock{}
+			s := NewServer("test_token", time.Hour, time.Second, tt.store, ntf)
+			s.client = tt.client
+
+			err := s.RunOnce(context.Background())
+			var se *StageError
+			assert.ErrorAs(t, err, &se)
+			assert.Equal(t, tt.stage, se.Stage)
+			assert.Equal(t, tt.title, ntf.title)
+			assert.Equal(t, se.Err.Error(), ntf.msg)
+		})
+	}
+}
+
+func TestStageError(t *testing.T) {
+	cause := errors.New("disk full")
+	err := error(&StageError{Stage: StageSave, Err: cause})
+	assert.EqualError(t, err, "save: disk full")
+	assert.ErrorIs(t, err, cause)
+}
+
+func TestServer_Prune(t *testing.T) {
+	store := newMemSaver()
+	for _, name := range []string{
+		"zen_2022-03-12_21-48-00.json",
+		"zen_2022-03-13_21-48-00.json",
+		"zen_2022-03-13_21-48-00.csv.gz",
+		"zen_2022-03-14_21-48-00.json",
+		"zen_2022-03-14_21-48-00.csv.gz",
+		"zen_state.json",
+	} {
+		assert.NoError(t, store.Save(context.Background(), name, []byte("{}")))
+	}
+	now := time.Date(2022, 3, 15, 0, 0, 0, 0, time.Local)
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{}, WithRetention(retention.Policy{KeepLast: 1}, false))
+
+	del, err := s.Prune(context.Background(), now, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zen_2022-03-12_21-48-00.json", "zen_2022-03-13_21-48-00.json", "zen_2022-03-13_21-48-00.csv.gz"}, del,
+		"exports are deleted with their backups")
+	assert.Len(t, store.files, 6, "dry run deletes nothing")
+
+	del, err = s.Prune(context.Background(), now, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zen_2022-03-12_21-48-00.json", "zen_2022-03-13_21-48-00.json", "zen_2022-03-13_21-48-00.csv.gz"}, del)
+	assert.Equal(t, []string{"zen_2022-03-14_21-48-00.csv.gz", "zen_2022-03-14_21-48-00.json", "zen_state.json"}, store.names())
+}
+
+func TestServer_saveExportWithRetention(t *testing.T) {
+	store, client := newMemSaver(), &syncerMock{}
+	assert.NoError(t, store.Save(context.Background(), "zen_2022-03-12_21-48-00.json", []byte("{}")))
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{}, WithRetention(retention.Policy{KeepLast: 1}, false))
+	s.client = client
+
+	s.saveExport(context.Background())
+	assert.Len(t, store.files, 1)
+	assert.NotContains(t, store.files, "zen_2022-03-12_21-48-00.json")
+}
+
+func TestServer_saveExportWithEncoders(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	enc, err := codec.NewAge([]string{id.Recipient().String()}, "")
+	assert.NoError(t, err)
+
+	store, client := newMemSaver(), &syncerMock{}
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{}, WithEncoders(enc))
+	s.client = client
+
+	s.saveExport(context.Background())
+	names := store.names()
+	assert.Len(t, names, 1)
+	assert.True(t, strings.HasSuffix(names[0], ".json.age"))
+	assert.NotContains(t, string(store.files[names[0]]), "serverTimestamp")
+}
+
+func TestServer_saveExportStream(t *testing.T) {
+	c, err := codec.NewCompressor("gzip", 0)
+	assert.NoError(t, err)
+	store, ntf := &streamSaver{memSaver: newMemSaver()}, &resultNotifierMock{}
+	clock := &fakeClock{now: time.Date(2022, 3, 12, 21, 48, 0, 0, time.Local)}
+	s := NewServer("test_token", time.Hour, time.Second, store, ntf,
+		WithEncoders(c), WithExporters(exporterMock{}), WithClock(clock))
+	s.client = &syncerMock{}
+
+	assert.NoError(t, s.RunOnce(context.Background()))
+	assert.Equal(t, []string{"zen_2022-03-12_21-48-00.json.gz", "zen_2022-03-12_21-48-00.txt.gz"}, store.streamed)
+	bs, err := codec.NewDecoder(nil).Decode(store.streamed[0], store.files[store.streamed[0]])
+	assert.NoError(t, err)
+	assert.Contains(t, string(bs), "serverTimestamp")
+	assert.Len(t, ntf.results, 1)
+	assert.Equal(t, len(store.files[store.streamed[0]]), ntf.results[0].Size)
+	assert.Empty(t, ntf.results[0].Hash, "data is hashed only for dedup")
+
+	store = &streamSaver{memSaver: newMemSaver()}
+	s = NewServer("test_token", time.Hour, time.Second, store, &notifierMock{},
+		WithExporters(exporterMock{err: errors.New("bad data")}), WithClock(clock))
+	s.client = &syncerMock{}
+	assert.EqualError(t, s.RunOnce(context.Background()), "encode: failed to export .txt: bad data")
+	assert.Equal(t, []string{"zen_2022-03-12_21-48-00.json"}, store.names(), "failed export isn't saved")
+
+	store.err = errors.New("disk full")
+	err = s.RunOnce(context.Background())
+	assert.EqualError(t, err, "save: disk full")
+	var stageErr *StageError
+	if assert.ErrorAs(t, err, &stageErr) {
+		assert.Equal(t, StageSave, stageErr.Stage)
+	}
+}
+
+func TestServer_saveExportWithLayout(t *testing.T) {
+	gz, err := codec.NewCompressor("gzip", 0)
+	assert.NoError(t, err)
+	store, client := newMemSaver(), &syncerMock{}
+	clock := &fakeClock{now: time.Date(2022, 3, 12, 21, 48, 0, 0, time.Local)}
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{}, WithLayout(codec.NewSplit("test")),
+		WithEncoders(gz), WithExporters(exporterMock{}), WithIncremental(24*time.Hour), WithClock(clock))
+	s.client = client
+
+	assert.NoError(t, s.RunOnce(context.Background()))
+	clock.now = clock.now.Add(time.Hour)
+	assert.NoError(t, s.RunOnce(context.Background()))
+	assert.Equal(t, []string{"zen_2022-03-12_21-48-00.json.tar.gz", "zen_2022-03-12_21-48-00.txt.gz",
+		"zen_2022-03-12_22-48-00.delta.json.tar.gz", "zen_state.json"}, store.names(), "exports are not split")
+
+	plain, err := codec.NewDecoder(nil).Decode("zen_2022-03-12_21-48-00.json.tar.gz", store.files["zen_2022-03-12_21-48-00.json.tar.gz"])
+	assert.NoError(t, err)
+	var resp models.Response
+	assert.NoError(t, json.Unmarshal(plain, &resp))
+	assert.Equal(t, 100, resp.ServerTimestamp)
+	assert.Equal(t, "full", resp.Tag[0].ID)
+}
+
+// exporterMock writes number of tags.
+type exporterMock struct{ err error }
+
+func (e exporterMock) Ext() string { return ".txt" }
+
+func (e exporterMock) Export(w io.Writer, resp models.Response) error {
+	if e.err != nil {
+		return e.err
+	}
+	_, err := fmt.Fprintf(w, "tags: %d", len(resp.Tag))
+	return err
+}
+
+// splitExporterMock writes a part per tag.
+type splitExporterMock struct{ exporterMock }
+
+func (e splitExporterMock) Ext() string { return ".tag" }
+
+func (e splitExporterMock) Parts(resp models.Response) []export.Part {
+	res := make([]export.Part, 0, len(resp.Tag))
+	for _, tag := range resp.Tag {
+		res = append(res, export.Part{Name: tag.ID, Export: func(w io.Writer) error {
+			_, err := io.WriteString(w, "tag "+tag.ID)
+			return err
+		}})
+	}
+	return res
+}
+
+func TestServer_saveExportWithExporters(t *testing.T) {
+	store, client := newMemSaver(), &syncerMock{}
+	start := time.Date(2022, 3, 12, 21, 48, 0, 0, time.Local)
+	clock := &fakeClock{now: start}
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{},
+		WithExporters(exporterMock{}, splitExporterMock{}), WithIncremental(24*time.Hour), WithClock(clock))
+	s.client = client
+
+	assert.NoError(t, s.RunOnce(context.Background()))
+	assert.Equal(t, "tags: 1", string(store.files["zen_2022-03-12_21-48-00.txt"]))
+	assert.Equal(t, "tag full", string(store.files["zen_2022-03-12_21-48-00.full.tag"]))
+	assert.Contains(t, store.files, "zen_2022-03-12_21-48-00.json")
+
+	clock.now = start.Add(time.Hour)
+	assert.NoError(t, s.RunOnce(context.Background()))
+	assert.Contains(t, store.files, "zen_2022-03-12_22-48-00.delta.json")
+	assert.NotContains(t, store.files, "zen_2022-03-12_22-48-00.txt", "deltas are not exported")
+
+	s = NewServer("test_token", time.Hour, time.Second, newMemSaver(), &notifierMock{},
+		WithExporters(exporterMock{err: errors.New("bad data")}))
+	s.client = &syncerMock{}
+	err := s.RunOnce(context.Background())
+	assert.EqualError(t, err, "encode: failed to export .txt: bad data")
+	var stageErr *StageError
+	if assert.ErrorAs(t, err, &stageErr) {
+		assert.Equal(t, StageEncode, stageErr.Stage)
+	}
+}
+
+// mirrorMock records applied data.
+type mirrorMock struct {
+	applied []models.Response
+	full    []bool
+	err     error
+}
+
+func (m *mirrorMock) Apply(_ context.Context, resp models.Response, full bool) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.applied = append(m.applied, resp)
+	m.full = append(m.full, full)
+	return nil
+}
+
+func TestServer_saveExportWithMirror(t *testing.T) {
+	store, client, m := newMemSaver(), &syncerMock{}, &mirrorMock{}
+	clock := &fakeClock{now: time.Date(2022, 3, 12, 21, 48, 0, 0, time.Local)}
+	s := NewServer("test_token", time.Hour, time.Second, store, &notifierMock{},
+		WithMirror(m), WithIncremental(24*time.Hour), WithDedup(), WithClock(clock))
+	s.client = client
+
+	assert.NoError(t, s.RunOnce(context.Background()))
+	clock.now = clock.now.Add(time.Hour)
+	assert.NoError(t, s.RunOnce(context.Background()))
+	client.noChanges = true
+	clock.now = clock.now.Add(time.Hour)
+	assert.NoError(t, s.RunOnce(context.Background()))
+	assert.Equal(t, []bool{true, false, false}, m.full, "deltas are applied, skipped ones too")
+	assert.Equal(t, "full", m.applied[0].Tag[0].ID)
+	assert.Equal(t, "gone", m.applied[1].Deletion[0].ID)
+
+	m.err = errors.New("database is locked")
+	client.noChanges = false
+	clock.now = clock.now.Add(time.Hour)
+	err := s.RunOnce(context.Background())
+	assert.EqualError(t, err, "mirror: database is locked")
+	assert.False(t, retryable(err))
+	st, err := s.loadState(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 300, st.ServerTimestamp, "changes are downloaded again by the next run")
+}
+
+func TestServer_profile(t *testing.T) {
+	store, client, notifier := newMemSaver(), &syncerMock{}, &resultNotifierMock{}
+	assert.NoError(t, store.Save(context.Background(), "zen_2022-03-12_21-48-00.json", []byte("{}")))
+	assert.NoError(t, store.Save(context.Background(), "zen_work_2022-03-12_21-48-00.json", []byte("{}")))
+	s := NewServer("test_token", time.Hour, time.Second, store, notifier, WithProfile("home"),
+		WithDedup(), WithRetention(retention.Policy{KeepLast: 1}, false))
+	s.client = client
+	assert.Equal(t, "home", s.Profile())
+
+	bT := time.Date(2022, 3, 12, 21, 48, 0, 0, time.UTC)
+	assert.Equal(t, "zen_home_2022-03-12_21-48-00.json", s.genFileName(bT))
+	assert.Equal(t, "zen_home_2022-03-12_21-48-00.delta.json", s.genDeltaFileName(bT))
+
+	assert.NoError(t, s.RunOnce(context.Background()))
+	assert.Contains(t, store.files, "zen_home_state.json")
+	assert.NotContains(t, store.files, "zen_state.json")
+	assert.Contains(t, store.files, "zen_2022-03-12_21-48-00.json", "other profiles are not pruned")
+	assert.Contains(t, store.files, "zen_work_2022-03-12_21-48-00.json", "other profiles are not pruned")
+	assert.Len(t, notifier.results, 1)
+	assert.Equal(t, "home", notifier.results[0].Profile)
+	assert.True(t, strings.HasPrefix(notifier.results[0].File, "zen_home_"))
+
+	client.err = errors.New("INVALID_TOKEN: token is not provided")
+	assert.Error(t, s.RunOnce(context.Background()))
+	assert.Equal(t, "home: Backup Export Error", notifier.title)
+}
@@ -0,0 +1,46 @@
+package srv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"time"
+)
+
+// state is persisted alongside backups between runs to make incremental backups
+// and to skip duplicates.
+type state struct {
+	ServerTimestamp int       `json:"serverTimestamp"` // of the last saved backup, full or delta
+	Base            string    `json:"base"`            // file name of the last full backup
+	BaseTime        time.Time `json:"baseTime"`
+	Hash            string    `json:"hash,omitempty"` // sha256 of normalized data of the last full backup
+	Last            string    `json:"last,omitempty"` // file name of the last saved backup, full or delta
+}
+
+// loadState reads state of the previous run, empty state is returned if there is none.
+func (srv *Server) loadState(ctx context.Context) (state, error) {
+	var st state
+	bs, err := srv.store.Load(ctx, srv.stateFileName())
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(bs, &st)
+	return st, err
+}
+
+func (srv *Server) saveState(ctx context.Context, st state) error {
+	bs, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return srv.store.Save(ctx, srv.stateFileName(), bs)
+}
+
+// stateFileName returns name of the state file, zen_state.json or zen_<profile>_state.json.
+func (srv *Server) stateFileName() string {
+	return srv.filePrefix() + "state.json"
+}
@@ -0,0 +1,50 @@
+package srv
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Status is the state of the backup loop, reported by the HTTP server.
+type Status struct {
+	ClientReady bool      `json:"clientReady"`
+	Running     bool      `json:"running"` // backup is being made right now
+	LastSuccess time.Time `json:"lastSuccess,omitzero"`
+	LastFailure time.Time `json:"lastFailure,omitzero"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastFile    string    `json:"lastFile,omitempty"`
+	LastSize    int       `json:"lastSize,omitempty"`
+	NextRun     time.Time `json:"nextRun,omitzero"`
+}
+
+// Status returns current status of Server.
+func (srv *Server) Status() Status {
+	srv.statusMu.Lock()
+	defer srv.statusMu.Unlock()
+	return srv.status
+}
+
+func (srv *Server) updateStatus(fn func(st *Status)) {
+	srv.statusMu.Lock()
+	defer srv.statusMu.Unlock()
+	fn(&srv.status)
+}
+
+// ready returns error if Server isn't ready: API client isn't created yet, or there was
+// no successful backup for maxAge. Zero maxAge disables the backup age check.
+func (st Status) ready(now time.Time, maxAge time.Duration) error {
+	if !st.ClientReady {
+		return errors.New("api client is not created")
+	}
+	if maxAge == 0 {
+		return nil
+	}
+	if st.LastSuccess.IsZero() {
+		return errors.New("no successful backup yet")
+	}
+	if age := now.Sub(st.LastSuccess); age > maxAge {
+		return fmt.Errorf("last successful backup is %s old", age.Truncate(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package srv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus_ready(t *testing.T) {
+	now := time.Date(2024, 6, 29, 15, 30, 0, 0, time.UTC)
+	assert.EqualError(t, Status{}.ready(now, time.Hour), "api client is not created")
+	assert.NoError(t, Status{ClientReady: true}.ready(now, 0))
+	assert.EqualError(t, Status{ClientReady: true}.ready(now, time.Hour), "no successful backup yet")
+	assert.NoError(t, Status{ClientReady: true, LastSuccess: now.Add(-time.Minute)}.ready(now, time.Hour))
+	assert.EqualError(t, Status{ClientReady: true, LastSuccess: now.Add(-2 * time.Hour)}.ready(now, time.Hour),
+		"last successful backup is 2h0m0s old")
+}
@@ -2,6 +2,10 @@
 package store
 
 import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
@@ -13,7 +17,7 @@ const downloadDirPerm = 0o750
 type LocalFs struct{}
 
 // Save performs writing file to disk.
-func (l LocalFs) Save(filename string, bs []byte) error {
+func (l LocalFs) Save(_ context.Context, filename string, bs []byte) error {
 	err := createDownloadDir()
 	if err != nil {
 		return err
@@ -22,6 +26,58 @@ func (l LocalFs) Save(filename string, bs []byte) error {
 	return err
 }
 
+// SaveStream performs writing file read from r to disk. The file is written under a temporary name and
+// renamed when r is read to the end, so a failed write leaves no partial file behind.
+func (l LocalFs) SaveStream(_ context.Context, filename string, r io.Reader) error {
+	if err := createDownloadDir(); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(downloadDir, "."+filename+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }() // no-op after rename
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(".", downloadDir, filename))
+}
+
+// List returns names of all files saved to disk.
+func (l LocalFs) List(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(downloadDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			res = append(res, e.Name())
+		}
+	}
+	return res, nil
+}
+
+// Load performs reading file from disk.
+func (l LocalFs) Load(_ context.Context, filename string) ([]byte, error) {
+	// #nosec G304 - filename is a backup name produced by the app
+	return os.ReadFile(filepath.Join(".", downloadDir, filepath.Base(filename)))
+}
+
 func createDownloadDir() error {
 	return os.MkdirAll(downloadDir, os.FileMode(downloadDirPerm))
 }
+
+// Delete performs removing file from disk.
+func (l LocalFs) Delete(_ context.Context, filename string) error {
+	return os.Remove(filepath.Join(".", downloadDir, filepath.Base(filename)))
+}
@@ -1,9 +1,13 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,7 +27,7 @@ func TestLocalFs_Save(t *testing.T) {
 	lfs := LocalFs{}
 
 	fName, content := "file1.txt", []byte("content 1")
-	err := lfs.Save(fName, content)
+	err := lfs.Save(context.Background(), fName, content)
 	assert.NoError(t, err)
 	assert.True(t, isDirExist(downloadDir))
 	assert.True(t, isDirExist(path.Join(downloadDir, fName)))
@@ -33,7 +37,7 @@ func TestLocalFs_Save(t *testing.T) {
 	assert.Equal(t, string(content), string(bs))
 
 	fName, content = "file2.txt", []byte("content 2")
-	err = lfs.Save(fName, content)
+	err = lfs.Save(context.Background(), fName, content)
 	assert.NoError(t, err)
 	assert.True(t, isDirExist(downloadDir))
 	assert.True(t, isDirExist(path.Join(downloadDir, fName)))
@@ -45,6 +49,73 @@ func TestLocalFs_Save(t *testing.T) {
 	tearDown()
 }
 
+func TestLocalFs_SaveStream(t *testing.T) {
+	lfs := LocalFs{}
+
+	err := lfs.SaveStream(context.Background(), "file1.txt", strings.NewReader("content 1"))
+	assert.NoError(t, err)
+	bs, err := lfs.Load(context.Background(), "file1.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "content 1", string(bs))
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(pw, "partial")
+		_ = pw.CloseWithError(errors.New("encoding failed"))
+	}()
+	err = lfs.SaveStream(context.Background(), "file2.txt", pr)
+	assert.EqualError(t, err, "encoding failed")
+	entries, err := os.ReadDir(downloadDir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "no partial or temporary file is left")
+
+	tearDown()
+}
+
+func TestLocalFs_Load(t *testing.T) {
+	lfs := LocalFs{}
+
+	_, err := lfs.Load(context.Background(), "nope.json")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	err = lfs.Save(context.Background(), "file1.txt", []byte("content 1"))
+	assert.NoError(t, err)
+	bs, err := lfs.Load(context.Background(), "file1.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "content 1", string(bs))
+
+	tearDown()
+}
+
+func TestLocalFs_List(t *testing.T) {
+	lfs := LocalFs{}
+
+	names, err := lfs.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	assert.NoError(t, lfs.Save(context.Background(), "file1.txt", []byte("content 1")))
+	assert.NoError(t, lfs.Save(context.Background(), "file2.txt", []byte("content 2")))
+	assert.NoError(t, os.Mkdir(path.Join(downloadDir, "dir"), 0o750))
+
+	names, err = lfs.List(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file1.txt", "file2.txt"}, names)
+
+	tearDown()
+}
+
+func TestLocalFs_Delete(t *testing.T) {
+	lfs := LocalFs{}
+
+	assert.NoError(t, lfs.Save(context.Background(), "file1.txt", []byte("content 1")))
+	assert.NoError(t, lfs.Delete(context.Background(), "file1.txt"))
+	assert.False(t, isDirExist(path.Join(downloadDir, "file1.txt")))
+	assert.ErrorIs(t, lfs.Delete(context.Background(), "file1.txt"), fs.ErrNotExist)
+
+	tearDown()
+}
+
 func Test_createDownloadDir(t *testing.T) {
 	assert.False(t, isDirExist(downloadDir))
 	err := createDownloadDir()
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3DefaultRegion  = "us-east-1"
+	s3RequestTimeout = 5 * time.Minute
+	s3TimeFormat     = "20060102T150405Z"
+	s3DateFormat     = "20060102"
+)
+
+// S3Opts is a set of S3 connection settings.
+type S3Opts struct {
+	Endpoint  string // e.g. https://s3.amazonaws.com or http://localhost:9000, AWS endpoint for Region if empty
+	Bucket    string
+	Prefix    string // key prefix inside the bucket, e.g. "zenmoney/"
+	Region    string
+	AccessKey string // anonymous requests if empty
+	SecretKey string
+	PathStyle bool // use https://host/bucket/key instead of https://bucket.host/key (MinIO)
+}
+
+// S3 is Saver to S3-compatible object storage (AWS, MinIO, Backblaze B2, Wasabi).
+type S3 struct {
+	opts     S3Opts
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 makes S3 storage from options.
+func NewS3(opts S3Opts) (*S3, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if opts.Region == "" {
+		opts.Region = s3DefaultRegion
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+	if !strings.Contains(opts.Endpoint, "://") {
+		opts.Endpoint = "https://" + opts.Endpoint
+	}
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q: no host", opts.Endpoint)
+	}
+	opts.Prefix = strings.Trim(opts.Prefix, "/")
+
+	return &S3{
+		opts:     opts,
+		endpoint: u,
+		client:   &http.Client{Timeout: s3RequestTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// Save performs uploading file to the bucket.
+func (s *S3) Save(ctx context.Context, filename string, bs []byte) error {
+	_, err := s.do(ctx, http.MethodPut, s.objectURL(filename), bs)
+	return err
+}
+
+// Load performs downloading file from the bucket.
+func (s *S3) Load(ctx context.Context, filename string) ([]byte, error) {
+	bs, err := s.do(ctx, http.MethodGet, s.objectURL(filename), nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("s3 object %s: %w", filename, err)
+	}
+	return bs, err
+}
+
+// Delete performs removing file from the bucket.
+func (s *S3) Delete(ctx context.Context, filename string) error {
+	_, err := s.do(ctx, http.MethodDelete, s.objectURL(filename), nil)
+	return err
+}
+
+// List returns names of all files under the prefix.
+func (s *S3) List(ctx context.Context) ([]string, error) {
+	prefix := ""
+	if s.opts.Prefix != "" {
+		prefix = s.opts.Prefix + "/"
+	}
+
+	var res []string
+	token := ""
+	for {
+		q := url.Values{"list-type": {"2"}, "prefix": {prefix}, "delimiter": {"/"}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u := s.objectURL("")
+		bs, err := s.do(ctx, http.MethodGet, u+"?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(bs, &page); err != nil {
+			return nil, fmt.Errorf("can't parse s3 list response: %w", err)
+		}
+		for _, c := range page.Contents {
+			res = append(res, strings.TrimPrefix(c.Key, prefix))
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return res, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// do performs signed request and returns response body, the request is canceled with ctx.
+func (s *S3) do(ctx context.Context, method, rawURL string, payload []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s3RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(payload))
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	s.sign(req, payload)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotFound {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fs.ErrNotExist
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, s3Error(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// objectURL returns full URL of the object, taking bucket addressing style into account.
+// URL of the bucket itself is returned for empty filename.
+func (s *S3) objectURL(filename string) string {
+	key := filename
+	if s.opts.Prefix != "" && filename != "" {
+		key = s.opts.Prefix + "/" + filename
+	}
+
+	u := *s.endpoint
+	if s.opts.PathStyle {
+		u.Path = path.Join("/", u.Path, s.opts.Bucket, key)
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = path.Join("/", u.Path, key)
+	}
+	u.RawPath = s3EscapePath(u.Path)
+	return u.String()
+}
+
+// sign adds AWS Signature Version 4 headers to the request. All headers
+// already present in the request are signed along with the host.
+func (s *S3) sign(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	if s.opts.AccessKey == "" {
+		return
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(s3DateFormat), s.opts.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(s3TimeFormat),
+		scope,
+		sha256Hex([]byte(canonRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), now.Format(s3DateFormat))
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// s3Error makes error from S3 XML error response.
+func s3Error(resp *http.Response) error {
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := xml.Unmarshal(body, &e); err != nil || e.Code == "" {
+		return fmt.Errorf("s3 request failed with status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("s3 request failed with status %d: %s: %s", resp.StatusCode, e.Code, e.Message)
+}
+
+// s3EscapePath encodes path according to RFC 3986 keeping slashes, as S3 expects.
+func s3EscapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = s3Escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vs := q[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, s3Escape(k)+"="+s3Escape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func sha256Hex(bs []byte) string {
+	h := sha256.Sum256(bs)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}